@@ -0,0 +1,210 @@
+package htma
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Expr is a Datastar expression, for use with the Data*Attr methods.
+type Expr string
+
+// String returns the expression.
+func (x Expr) String() string {
+	return string(x)
+}
+
+// And joins expressions with &&, so each runs only if the previous one is truthy.
+// Operands other than signal references and actions are parenthesised, so that
+// assignments made with Set keep their meaning:
+//
+//	And(Signal("valid"), Post("/bookings"))         // $valid && @post('/bookings')
+//	And(Signal("busy").Set(true), Post("/bookings")) // ($busy = true) && @post('/bookings')
+func And(exprs ...fmt.Stringer) Expr {
+	parts := make([]string, len(exprs))
+	for i, x := range exprs {
+		parts[i] = x.String()
+		switch x.(type) {
+		case SignalRef, Action:
+		default:
+			if !isSignalRef(parts[i]) {
+				parts[i] = "(" + parts[i] + ")"
+			}
+		}
+	}
+	return Expr(strings.Join(parts, " && "))
+}
+
+// SignalRef refers to a Datastar signal.
+type SignalRef struct {
+	name string
+}
+
+// Signal returns a reference to the named signal. Nested signals are named with dots,
+// as in "flight.status". Signal panics if a part of the name is not an identifier.
+func Signal(name string) SignalRef {
+	for _, part := range strings.Split(name, ".") {
+		if !isJSIdent(part) {
+			panic(fmt.Sprintf("htma: invalid signal name %q", name))
+		}
+	}
+	return SignalRef{name}
+}
+
+// String returns the reference, such as $flight.status.
+func (s SignalRef) String() string {
+	return "$" + s.name
+}
+
+// Set returns an expression assigning v to the signal. Expressions, signal references
+// and actions are used as they are; other values are encoded as JSON. Set panics if
+// v cannot be encoded.
+func (s SignalRef) Set(v any) Expr {
+	return Expr(s.String() + " = " + jsValue(v))
+}
+
+// Toggle returns an expression negating the signal.
+func (s SignalRef) Toggle() Expr {
+	return Expr(s.String() + " = !" + s.String())
+}
+
+// Action is a Datastar backend action, such as @get('/flights/UA123').
+type Action struct {
+	method      string
+	url         string
+	headers     [][2]string
+	contentType string
+	selector    string
+}
+
+// Get returns an action sending a GET request to url.
+func Get(url string) Action {
+	return Action{method: "get", url: url}
+}
+
+// Post returns an action sending a POST request to url.
+func Post(url string) Action {
+	return Action{method: "post", url: url}
+}
+
+// Put returns an action sending a PUT request to url.
+func Put(url string) Action {
+	return Action{method: "put", url: url}
+}
+
+// Patch returns an action sending a PATCH request to url.
+func Patch(url string) Action {
+	return Action{method: "patch", url: url}
+}
+
+// Delete returns an action sending a DELETE request to url.
+func Delete(url string) Action {
+	return Action{method: "delete", url: url}
+}
+
+// Header adds a request header.
+func (a Action) Header(key, value string) Action {
+	a.headers = append(a.headers[:len(a.headers):len(a.headers)], [2]string{key, value})
+	return a
+}
+
+// ContentType sets how signals are sent: "json", the default, or "form" to submit
+// the closest form, or the one chosen with Selector.
+func (a Action) ContentType(contentType string) Action {
+	a.contentType = contentType
+	return a
+}
+
+// Selector sets the form submitted when the content type is "form".
+func (a Action) Selector(selector string) Action {
+	a.selector = selector
+	return a
+}
+
+// String returns the action expression.
+func (a Action) String() string {
+	var b strings.Builder
+	b.WriteString("@" + a.method + "(" + jsString(a.url))
+	var opts []string
+	if len(a.headers) > 0 {
+		headers := make([]string, len(a.headers))
+		for i, h := range a.headers {
+			headers[i] = jsString(h[0]) + ": " + jsString(h[1])
+		}
+		opts = append(opts, "headers: {"+strings.Join(headers, ", ")+"}")
+	}
+	if a.contentType != "" {
+		opts = append(opts, "contentType: "+jsString(a.contentType))
+	}
+	if a.selector != "" {
+		opts = append(opts, "selector: "+jsString(a.selector))
+	}
+	if len(opts) > 0 {
+		b.WriteString(", {" + strings.Join(opts, ", ") + "}")
+	}
+	b.WriteString(")")
+	return b.String()
+}
+
+// jsValue returns v as a JavaScript operand.
+func jsValue(v any) string {
+	switch v := v.(type) {
+	case Expr, SignalRef, Action:
+		return v.(fmt.Stringer).String()
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		panic(fmt.Sprintf("htma: cannot encode %T as a Datastar value: %v", v, err))
+	}
+	return string(b)
+}
+
+// jsString returns s as a single-quoted JavaScript string literal.
+func jsString(s string) string {
+	var b strings.Builder
+	b.WriteByte('\'')
+	for _, c := range s {
+		switch c {
+		case '\\', '\'':
+			b.WriteByte('\\')
+			b.WriteRune(c)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\u2028', '\u2029':
+			fmt.Fprintf(&b, `\u%04x`, c)
+		default:
+			b.WriteRune(c)
+		}
+	}
+	b.WriteByte('\'')
+	return b.String()
+}
+
+// isSignalRef reports whether s is a plain signal reference, such as $flight.status.
+func isSignalRef(s string) bool {
+	if !strings.HasPrefix(s, "$") {
+		return false
+	}
+	for _, part := range strings.Split(s[1:], ".") {
+		if !isJSIdent(part) {
+			return false
+		}
+	}
+	return true
+}
+
+// isJSIdent reports whether s is a plain JavaScript identifier.
+func isJSIdent(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !(isASCIILetter(c) || c == '_' || c == '$' || i > 0 && '0' <= c && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,49 @@
+package htma
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestActions(t *testing.T) {
+	tests := []struct {
+		expr fmt.Stringer
+		want string
+	}{
+		{Get("/flights/UA123"), `@get('/flights/UA123')`},
+		{Post("/bookings").ContentType("form").Selector("#booking"), `@post('/bookings', {contentType: 'form', selector: '#booking'})`},
+		{Put("/seats/12A").Header("X-CSRF-Token", "abc"), `@put('/seats/12A', {headers: {'X-CSRF-Token': 'abc'}})`},
+		{Patch("/flights/it's"), `@patch('/flights/it\'s')`},
+		{Delete("/bookings/1"), `@delete('/bookings/1')`},
+		{Signal("open"), `$open`},
+		{Signal("flight.status").Set("Delayed"), `$flight.status = "Delayed"`},
+		{Signal("count").Set(Expr("$count + 1")), `$count = $count + 1`},
+		{Signal("seat").Set(Signal("selected")), `$seat = $selected`},
+		{Signal("open").Toggle(), `$open = !$open`},
+		{And(Signal("valid"), Post("/bookings")), `$valid && @post('/bookings')`},
+		{And(Signal("busy").Set(true), Post("/bookings")), `($busy = true) && @post('/bookings')`},
+		{And(Expr("$seats > 0"), Expr("$valid"), Get("/fares")), `($seats > 0) && $valid && @get('/fares')`},
+		{And(And(Signal("a"), Signal("b")), Signal("c")), `($a && $b) && $c`},
+	}
+	for _, tt := range tests {
+		if got := tt.expr.String(); got != tt.want {
+			t.Errorf("got  %s\nwant %s", got, tt.want)
+		}
+	}
+}
+
+func TestActionsInAttributes(t *testing.T) {
+	got := Button().DataOnClickAttr(And(Signal("valid"), Post("/bookings")).String()).Render()
+	if want := `<button data-on-click="$valid &amp;&amp; @post(&#39;/bookings&#39;)"></button>`; got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
+
+func TestSignalPanicsOnInvalidName(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Signal(\"a-b\") did not panic")
+		}
+	}()
+	Signal("a-b")
+}
@@ -0,0 +1,61 @@
+// Command html2htma converts HTML into Go source that builds the same markup
+// with the htma builders.
+//
+// Usage:
+//
+//	html2htma [-pkg name] [-func name] [file.html]
+//
+// The HTML is read from the named file, or from standard input if no file is given,
+// and the generated source is written to standard output.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/arcade55/htma"
+)
+
+func main() {
+	pkg := flag.String("pkg", "main", "package name of the generated file")
+	fn := flag.String("func", "Component", "name of the generated function")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: html2htma [-pkg name] [-func name] [file.html]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if err := run(os.Stdout, flag.Args(), htma.GoOptions{Package: *pkg, Func: *fn}); err != nil {
+		fmt.Fprintln(os.Stderr, "html2htma:", err)
+		os.Exit(1)
+	}
+}
+
+func run(w io.Writer, args []string, opts htma.GoOptions) error {
+	var in io.Reader = os.Stdin
+	switch len(args) {
+	case 0:
+	case 1:
+		f, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	default:
+		return fmt.Errorf("expected at most one input file, got %d", len(args))
+	}
+
+	nodes, err := htma.Parse(in)
+	if err != nil {
+		return err
+	}
+	src, err := htma.GenerateGo(nodes, opts)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(src)
+	return err
+}
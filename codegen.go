@@ -0,0 +1,180 @@
+package htma
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"strconv"
+	"strings"
+)
+
+// GoOptions controls the source produced by GenerateGo.
+type GoOptions struct {
+	Package string // package clause of the generated file; defaults to "main"
+	Func    string // name of the generated function; defaults to "Component"
+}
+
+// GenerateGo returns gofmt'ed Go source for a function that builds nodes with the
+// htma builders. Typed constructors and attribute methods are used where they exist,
+// falling back to CustomElement, Attr and BoolAttr.
+//
+// Whitespace-only text between block-level elements is dropped, as it does not
+// affect the rendered page; whitespace elsewhere is kept.
+func GenerateGo(nodes []Renderable, opts GoOptions) ([]byte, error) {
+	if opts.Package == "" {
+		opts.Package = "main"
+	}
+	if opts.Func == "" {
+		opts.Func = "Component"
+	}
+	g := &goGenerator{}
+
+	g.printf("package %s\n\nimport \"github.com/arcade55/htma\"\n\n", opts.Package)
+	if len(nodes) == 1 {
+		if _, ok := nodes[0].(Element); ok {
+			g.printf("func %s() htma.Element {\n\treturn ", opts.Func)
+		} else {
+			g.printf("func %s() htma.Renderable {\n\treturn ", opts.Func)
+		}
+		if err := g.node(nodes[0]); err != nil {
+			return nil, err
+		}
+		g.printf("\n}\n")
+	} else {
+		g.printf("func %s() []htma.Renderable {\n\treturn []htma.Renderable{\n", opts.Func)
+		for _, n := range nodes {
+			if err := g.node(n); err != nil {
+				return nil, err
+			}
+			g.printf(",\n")
+		}
+		g.printf("}\n}\n")
+	}
+	return format.Source(g.buf.Bytes())
+}
+
+type goGenerator struct {
+	buf bytes.Buffer
+}
+
+func (g *goGenerator) printf(format string, args ...any) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+func (g *goGenerator) node(n Renderable) error {
+	switch n := n.(type) {
+	case Element:
+		return g.element(n)
+	case TextContent:
+		g.printf("htma.Content(%s)", strconv.Quote(n.Content))
+	case Raw:
+		g.printf("htma.RawContent(%s)", strconv.Quote(n.Content))
+	case Fragment:
+		g.printf("htma.Fragment{\n")
+		for _, c := range n {
+			if err := g.node(c); err != nil {
+				return err
+			}
+			g.printf(",\n")
+		}
+		g.printf("}")
+	default:
+		return fmt.Errorf("htma: cannot generate Go for %T", n)
+	}
+	return nil
+}
+
+func (g *goGenerator) element(e Element) error {
+	switch ctor, ok := elementConstructors[e.tag]; {
+	case e.tag == "title":
+		g.printf("htma.Title(%s)", strconv.Quote(e.text))
+		e.text = ""
+	case ok:
+		g.printf("htma.%s()", ctor.name)
+	default:
+		g.printf("htma.CustomElement(%s)", strconv.Quote(e.tag))
+	}
+
+	for _, a := range e.attrs {
+		g.attr(a)
+	}
+	if e.text != "" {
+		g.printf(".Text(%s)", strconv.Quote(e.text))
+	}
+	children := e.children
+	if !whitespaceSensitiveTags[e.tag] {
+		children = trimLayoutWhitespace(children)
+	}
+	if len(children) > 0 {
+		g.printf(".AddChild(\n")
+		for _, c := range children {
+			if err := g.node(c); err != nil {
+				return err
+			}
+			g.printf(",\n")
+		}
+		g.printf(")")
+	}
+	return nil
+}
+
+func (g *goGenerator) attr(a attribute) {
+	if a.boolean {
+		if m, ok := boolAttrMethods[a.key]; ok {
+			g.printf(".%s(true)", m.name)
+		} else {
+			g.printf(".BoolAttr(%s, true)", strconv.Quote(a.key))
+		}
+		return
+	}
+	if m, ok := intAttrMethods[a.key]; ok {
+		if n, err := strconv.Atoi(a.value); err == nil && strconv.Itoa(n) == a.value {
+			g.printf(".%s(%d)", m.name, n)
+			return
+		}
+	}
+	if m, ok := stringAttrMethods[a.key]; ok && methodKeepsValue(a.key, a.value) {
+		g.printf(".%s(%s)", m.name, strconv.Quote(a.value))
+		return
+	}
+	if data, ok := strings.CutPrefix(a.key, "data-"); ok && data != "" {
+		g.printf(".DataAttr(%s, %s)", strconv.Quote(data), strconv.Quote(a.value))
+		return
+	}
+	g.printf(".Attr(%s, %s)", strconv.Quote(a.key), strconv.Quote(a.value))
+}
+
+// methodKeepsValue reports whether the string method for key sets it to exactly value.
+// IDAttr rejects values with whitespace and ClassAttr normalizes the class list; the
+// other methods store the value as given.
+func methodKeepsValue(key, value string) bool {
+	switch key {
+	case "id":
+		return !strings.ContainsAny(value, " \t\n")
+	case "class":
+		return value != "" && value == strings.Join(strings.Fields(value), " ") && !strings.Contains(value, ";")
+	}
+	return true
+}
+
+// trimLayoutWhitespace drops whitespace-only text nodes from children when every
+// other child is a block-level element, so the whitespace is not visible on the page.
+func trimLayoutWhitespace(children []Renderable) []Renderable {
+	var kept []Renderable
+	for _, c := range children {
+		switch c := c.(type) {
+		case TextContent:
+			if strings.TrimSpace(c.Content) != "" {
+				return children
+			}
+		case Element:
+			if !blockTags[c.tag] {
+				return children
+			}
+			kept = append(kept, c)
+		default:
+			kept = append(kept, c)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,128 @@
+package htma
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerateGo(t *testing.T) {
+	src := `<results-card id="results"><flight-card ident="UA123" flight-number="123" tabindex="0"></flight-card>` +
+		`<md-filled-button data-on-click="@get('/flights')" disabled>Search</md-filled-button>` +
+		`<a href="/help" class="link  muted" data-tooltip="Help">Help</a><x-map zoom="3"></x-map></results-card>`
+	nodes, err := ParseFragment(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := GenerateGo(nodes, GoOptions{Package: "views", Func: "Results"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "TestGenerateGo", string(got))
+}
+
+// roundTripInputs lists HTML for the round-trip test. An empty want means the generated
+// code must render exactly what the parsed input renders.
+var roundTripInputs = []struct {
+	input string
+	want  string
+}{
+	{`<div class="grid"><div class="a">a</div><div class="b">b</div></div>`, ""},
+	{`<main class="main tile"><h1>Team collaboration done right</h1><p>Thousands of teams turn to <b>Ink</b> to get things done.</p></main>`, ""},
+	{`<form id="search" action="/flights" method="get"><input type="text" name="ident" maxlength="8" required autofocus><button type="submit" disabled>Go</button></form>`, ""},
+	{`<flight-card ident="UA123" origin-iata="SFO" dest-iata="EWR" data-on-click="@get('/flights/UA123')"></flight-card>`, ""},
+	{`<md-filled-button data-on-click="$open = true" data-show="$ready">Search</md-filled-button><md-dialog open></md-dialog>`, ""},
+	{`<ul><li>one &amp; two</li><li>"three"</li></ul><!-- end of list -->`, ""},
+	{`<script>if (a < b && c) { go("x"); }</script><style>a > b { color: red; }</style>`, ""},
+	{"<pre>  keep\n    this</pre><textarea name=\"notes\" rows=\"3\">a &lt; b</textarea>", ""},
+	{"<!DOCTYPE html><html lang=\"en\"><head><title>Flights</title><meta charset=\"utf-8\"></head><body><x-widget some-prop=\"1\" data-k=\"v\"></x-widget></body></html>", ""},
+	{"<section>\n  <h2>Indented</h2>\n  <p>Layout <em>whitespace</em> is dropped</p>\n</section>", `<section><h2>Indented</h2><p>Layout <em>whitespace</em> is dropped</p></section>`},
+}
+
+// TestGenerateGoRoundTrip compiles the generated code against this module and checks
+// that it renders the same markup as the parsed input.
+func TestGenerateGoRoundTrip(t *testing.T) {
+	if testing.Short() {
+		t.Skip("compiles generated code")
+	}
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go tool not found")
+	}
+	repo, err := filepath.Abs(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("go.mod", "module roundtrip\n\ngo 1.24\n\nrequire github.com/arcade55/htma v0.0.0\n\nreplace github.com/arcade55/htma => "+repo+"\n")
+
+	var want []string
+	var calls strings.Builder
+	for i, tt := range roundTripInputs {
+		nodes, err := Parse(strings.NewReader(tt.input))
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tt.input, err)
+		}
+		name := fmt.Sprintf("Case%d", i)
+		src, err := GenerateGo(nodes, GoOptions{Func: name})
+		if err != nil {
+			t.Fatalf("GenerateGo(%q): %v", tt.input, err)
+		}
+		write(fmt.Sprintf("case%d.go", i), string(src))
+		fmt.Fprintf(&calls, "\tprint(%s())\n", name)
+
+		if tt.want == "" {
+			tt.want = renderAll(t, nodes)
+		}
+		want = append(want, tt.want)
+	}
+	write("main.go", `package main
+
+import (
+	"os"
+
+	"github.com/arcade55/htma"
+)
+
+func print(v any) {
+	switch v := v.(type) {
+	case htma.Element:
+		v.RenderStream(os.Stdout)
+	case []htma.Renderable:
+		for _, r := range v {
+			r.RenderStream(os.Stdout)
+		}
+	}
+	os.Stdout.WriteString("\x00")
+}
+
+func main() {
+`+calls.String()+"}\n")
+
+	cmd := exec.Command(goTool, "run", ".")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOWORK=off")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("go run: %v\n%s", err, out)
+	}
+	got := strings.Split(strings.TrimSuffix(string(out), "\x00"), "\x00")
+	if len(got) != len(want) {
+		t.Fatalf("got %d outputs, want %d:\n%s", len(got), len(want), out)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("case %d:\ngot  %s\nwant %s", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package htma
+
+import (
+	"context"
+	"io"
+	"strings"
+)
+
+// RenderError reports a failure while rendering a tree, with the path of the
+// element in which it happened, such as "html>body>main>results-card[2]".
+type RenderError struct {
+	Path string
+	Err  error
+}
+
+func (e *RenderError) Error() string {
+	return "htma: rendering " + e.Path + ": " + e.Err.Error()
+}
+
+func (e *RenderError) Unwrap() error {
+	return e.Err
+}
+
+// wrapRenderError adds segment to the front of err's element path.
+func wrapRenderError(err error, segment string) error {
+	if re, ok := err.(*RenderError); ok {
+		return &RenderError{Path: segment + ">" + re.Path, Err: re.Err}
+	}
+	return &RenderError{Path: segment, Err: err}
+}
+
+// RenderString renders r and returns the HTML, or the first error encountered.
+// It is the error-returning counterpart of Render.
+func RenderString(r Renderable) (string, error) {
+	var b strings.Builder
+	if err := r.RenderStream(&b); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// RenderFunc is a node that writes its own markup and may fail.
+type RenderFunc func(w io.Writer) error
+
+// Func creates a node that calls fn to write its markup when rendered. The output of
+// fn is written verbatim, so it must escape any untrusted content itself.
+func Func(fn func(w io.Writer) error) RenderFunc {
+	return RenderFunc(fn)
+}
+
+// Render returns the markup written by the function, discarding any error.
+func (f RenderFunc) Render() string {
+	s, _ := RenderString(f)
+	return s
+}
+
+// RenderStream calls the function with w.
+func (f RenderFunc) RenderStream(w io.Writer) error {
+	return f(w)
+}
+
+// Component is implemented by types that build their markup on demand, such as a
+// card that looks up a flight's status when the page is rendered.
+type Component interface {
+	Build() (Renderable, error)
+}
+
+// ComponentFunc adapts a function to both Component and Renderable. The function is
+// called each time the node is rendered, and its error is returned from RenderStream.
+type ComponentFunc func() (Renderable, error)
+
+// Lazy returns a node that builds c when it is rendered rather than when the tree is built.
+func Lazy(c Component) ComponentFunc {
+	return c.Build
+}
+
+// Build calls the function.
+func (f ComponentFunc) Build() (Renderable, error) {
+	return f()
+}
+
+// Render returns the component's HTML, discarding any error.
+func (f ComponentFunc) Render() string {
+	s, _ := RenderString(f)
+	return s
+}
+
+// RenderStream builds the component and writes it to w.
+func (f ComponentFunc) RenderStream(w io.Writer) error {
+	return f.renderNode(newRenderer(context.Background(), w, Options{}))
+}
+
+// RenderContext builds the component and writes it to w, passing ctx to the result.
+func (f ComponentFunc) RenderContext(ctx context.Context, w io.Writer) error {
+	return f.renderNode(newRenderer(ctx, w, optionsFrom(ctx)))
+}
+
+func (f ComponentFunc) renderNode(r *renderer) error {
+	node, err := f()
+	if err != nil {
+		return err
+	}
+	return renderBuilt(r, node)
+}
+
+// renderBuilt renders the node produced by a component, naming it in any error path.
+func renderBuilt(r *renderer, node Renderable) error {
+	if err := r.renderChild(node); err != nil {
+		if e, ok := node.(Element); ok {
+			return wrapRenderError(err, e.tag)
+		}
+		return err
+	}
+	return nil
+}
+
+// RenderContextFunc is a node that writes its own markup using a request-scoped context.
+type RenderContextFunc func(ctx context.Context, w io.Writer) error
+
+// FuncContext creates a node that calls fn with the render context to write its markup.
+// As with Func, the output is written verbatim. Rendered without a context, such as
+// through RenderStream, fn receives context.Background().
+func FuncContext(fn func(ctx context.Context, w io.Writer) error) RenderContextFunc {
+	return RenderContextFunc(fn)
+}
+
+// Render returns the markup written by the function, discarding any error.
+func (f RenderContextFunc) Render() string {
+	s, _ := RenderString(f)
+	return s
+}
+
+// RenderStream calls the function with a background context.
+func (f RenderContextFunc) RenderStream(w io.Writer) error {
+	return f(context.Background(), w)
+}
+
+// RenderContext calls the function.
+func (f RenderContextFunc) RenderContext(ctx context.Context, w io.Writer) error {
+	return f(ctx, w)
+}
+
+// ContextComponentFunc is like ComponentFunc, but builds its node from the render
+// context, for components that need request-scoped values such as the current user
+// or locale.
+type ContextComponentFunc func(ctx context.Context) (Renderable, error)
+
+// Render returns the component's HTML, discarding any error.
+func (f ContextComponentFunc) Render() string {
+	s, _ := RenderString(f)
+	return s
+}
+
+// RenderStream builds the component with a background context and writes it to w.
+func (f ContextComponentFunc) RenderStream(w io.Writer) error {
+	return f.RenderContext(context.Background(), w)
+}
+
+// RenderContext builds the component with ctx and writes it to w.
+func (f ContextComponentFunc) RenderContext(ctx context.Context, w io.Writer) error {
+	return f.renderNode(newRenderer(ctx, w, optionsFrom(ctx)))
+}
+
+func (f ContextComponentFunc) renderNode(r *renderer) error {
+	node, err := f(r.ctx)
+	if err != nil {
+		return err
+	}
+	return renderBuilt(r, node)
+}
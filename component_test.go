@@ -0,0 +1,144 @@
+package htma
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+var errLookup = errors.New("flight status unavailable")
+
+// statusCard looks up a flight's status when rendered.
+type statusCard struct {
+	ident  string
+	status map[string]string
+}
+
+func (c statusCard) Build() (Renderable, error) {
+	s, ok := c.status[c.ident]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", c.ident, errLookup)
+	}
+	return FlightCard().IdentAttr(c.ident).StatusTextAttr(s), nil
+}
+
+func TestComponentRendering(t *testing.T) {
+	status := map[string]string{"UA1": "Boarding"}
+	calls := 0
+	page := Main().AddChild(
+		Lazy(statusCard{ident: "UA1", status: status}),
+		Func(func(w io.Writer) error {
+			calls++
+			_, err := io.WriteString(w, "<hr>")
+			return err
+		}),
+	)
+	if calls != 0 {
+		t.Fatal("Func was called while building the tree")
+	}
+	got, err := RenderString(page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `<main><flight-card ident="UA1" status-text="Boarding"></flight-card><hr></main>`; got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+	status["UA1"] = "Departed"
+	if got := page.Render(); !strings.Contains(got, "Departed") {
+		t.Errorf("component was not rebuilt on the second render: %s", got)
+	}
+}
+
+func TestRenderErrorPath(t *testing.T) {
+	status := map[string]string{"UA1": "Boarding"}
+	page := HTML().AddChild(
+		Head().AddChild(Title("Flights")),
+		Body().AddChild(
+			Main().AddChild(
+				ResultsCard().AddChild(Lazy(statusCard{ident: "UA1", status: status})),
+				ResultsCard().AddChild(Lazy(statusCard{ident: "UA2", status: status})),
+			),
+		),
+	)
+
+	_, err := RenderString(page)
+	var re *RenderError
+	if !errors.As(err, &re) {
+		t.Fatalf("error = %v, want *RenderError", err)
+	}
+	if want := "html>body>main>results-card[2]"; re.Path != want {
+		t.Errorf("Path = %q, want %q", re.Path, want)
+	}
+	if !errors.Is(err, errLookup) {
+		t.Errorf("error %v does not wrap the component's error", err)
+	}
+	if want := "htma: rendering html>body>main>results-card[2]: UA2: flight status unavailable"; err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+}
+
+func TestRenderErrorPathInsideComponent(t *testing.T) {
+	failing := Func(func(io.Writer) error { return errLookup })
+	component := ComponentFunc(func() (Renderable, error) {
+		return Section().AddChild(P(), P().AddChild(failing)), nil
+	})
+	err := Fragment{Div(), Div().AddChild(component)}.RenderStream(io.Discard)
+	var re *RenderError
+	if !errors.As(err, &re) {
+		t.Fatalf("error = %v, want *RenderError", err)
+	}
+	if want := "div[2]>section>p[2]"; re.Path != want {
+		t.Errorf("Path = %q, want %q", re.Path, want)
+	}
+}
+
+type userKey struct{}
+
+func TestRenderContextReachesNestedComponents(t *testing.T) {
+	greeting := ContextComponentFunc(func(ctx context.Context) (Renderable, error) {
+		user, _ := ctx.Value(userKey{}).(string)
+		return Span().Text("Hello, " + user), nil
+	})
+	locale := FuncContext(func(ctx context.Context, w io.Writer) error {
+		_, err := io.WriteString(w, "<!-- signed in -->")
+		return err
+	})
+	page := Body().AddChild(Header().AddChild(Nav().AddChild(greeting, locale)))
+
+	ctx := context.WithValue(context.Background(), userKey{}, "Ada")
+	var b strings.Builder
+	if err := page.RenderContext(ctx, &b); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := b.String(), `<body><header><nav><span>Hello, Ada</span><!-- signed in --></nav></header></body>`; got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+	if got, want := page.Render(), `<body><header><nav><span>Hello, </span><!-- signed in --></nav></header></body>`; got != want {
+		t.Errorf("without context: got %s, want %s", got, want)
+	}
+}
+
+func TestRenderContextStopsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	rows := Each(make([]int, 100), func(i int, _ int) Renderable {
+		if i == 3 {
+			// Simulate the client disconnecting part way through the page.
+			return FuncContext(func(context.Context, io.Writer) error {
+				cancel()
+				return nil
+			})
+		}
+		return Tr()
+	})
+	var b strings.Builder
+	err := Table().AddChild(Tbody().AddChild(rows)).RenderContext(ctx, &b)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("error = %v, want context.Canceled", err)
+	}
+	if got, want := b.String(), "<table><tbody><tr></tr><tr></tr><tr></tr>"; got != want {
+		t.Errorf("rendered %q after cancellation, want %q", got, want)
+	}
+}
@@ -0,0 +1,64 @@
+package htma
+
+// If returns r when cond is true and an empty node otherwise.
+// Both branches are built before If is called; use IfFunc when building r
+// is only valid if cond holds, such as when it dereferences a nil pointer.
+func If(cond bool, r Renderable) Renderable {
+	if cond {
+		return r
+	}
+	return Fragment(nil)
+}
+
+// IfFunc returns the result of fn when cond is true and an empty node otherwise.
+// fn is not called when cond is false.
+func IfFunc(cond bool, fn func() Renderable) Renderable {
+	if cond {
+		return fn()
+	}
+	return Fragment(nil)
+}
+
+// IfElse returns a when cond is true and b otherwise.
+func IfElse(cond bool, a, b Renderable) Renderable {
+	if cond {
+		return a
+	}
+	return b
+}
+
+// Each calls fn for every item, with its index, and returns the results as a Fragment.
+// It plays the role of a Map helper, a name already taken by the <map> element constructor.
+func Each[T any](items []T, fn func(i int, item T) Renderable) Fragment {
+	out := make(Fragment, 0, len(items))
+	for i, item := range items {
+		out = append(out, fn(i, item))
+	}
+	return out
+}
+
+// SwitchCase is one branch of a Switch, created with Case or Default.
+type SwitchCase struct {
+	matches bool
+	node    Renderable
+}
+
+// Case returns a branch that is chosen when cond is true.
+func Case(cond bool, r Renderable) SwitchCase {
+	return SwitchCase{matches: cond, node: r}
+}
+
+// Default returns a branch that is always chosen if no earlier branch was.
+func Default(r Renderable) SwitchCase {
+	return SwitchCase{matches: true, node: r}
+}
+
+// Switch returns the node of the first matching case, or an empty node if none match.
+func Switch(cases ...SwitchCase) Renderable {
+	for _, c := range cases {
+		if c.matches {
+			return c.node
+		}
+	}
+	return Fragment(nil)
+}
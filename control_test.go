@@ -0,0 +1,75 @@
+package htma
+
+import "testing"
+
+type testFlight struct {
+	Ident   string
+	Status  string
+	Delayed bool
+}
+
+func flightResults(flights []testFlight, loggedIn bool) Element {
+	return ResultsCard().AddChild(
+		If(len(flights) == 0, P().Text("No flights found")),
+		Each(flights, func(i int, f testFlight) Renderable {
+			return FlightCard().
+				IdentAttr(f.Ident).
+				ClassIf(f.Delayed, "delayed").
+				AttrIf(i == 0, "data-first", "true").
+				AddChild(Switch(
+					Case(f.Status == "boarding", Span().Text("Boarding")),
+					Case(f.Status == "departed", Span().Text("Departed")),
+					Default(Span().Text("Scheduled")),
+				))
+		}),
+		IfElse(loggedIn, A().HrefAttr("/trips").Text("My trips"), A().HrefAttr("/login").Text("Sign in")),
+	)
+}
+
+func TestControlFlowHelpers(t *testing.T) {
+	tests := []struct {
+		name     string
+		flights  []testFlight
+		loggedIn bool
+		want     string
+	}{
+		{
+			"empty",
+			nil,
+			false,
+			`<results-card><p>No flights found</p><a href="/login">Sign in</a></results-card>`,
+		},
+		{
+			"flights",
+			[]testFlight{{"UA1", "boarding", false}, {"UA2", "", true}, {"UA3", "departed", false}},
+			true,
+			`<results-card>` +
+				`<flight-card ident="UA1" data-first="true"><span>Boarding</span></flight-card>` +
+				`<flight-card ident="UA2" class="delayed"><span>Scheduled</span></flight-card>` +
+				`<flight-card ident="UA3"><span>Departed</span></flight-card>` +
+				`<a href="/trips">My trips</a></results-card>`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := flightResults(tt.flights, tt.loggedIn).Render(); got != tt.want {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIfFuncIsLazy(t *testing.T) {
+	var f *testFlight
+	got := Div().AddChild(IfFunc(f != nil, func() Renderable { return Span().Text(f.Ident) })).Render()
+	if want := `<div></div>`; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestSwitchWithoutMatch(t *testing.T) {
+	got := Div().AddChild(Switch(Case(false, Span()))).Render()
+	if want := `<div></div>`; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
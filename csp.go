@@ -0,0 +1,81 @@
+package htma
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"strings"
+)
+
+// DefaultCSP is a Content-Security-Policy for pages whose script and style elements
+// carry the request nonce. The {nonce} placeholder is replaced per request.
+// script-src includes 'unsafe-eval' because Datastar evaluates data-* expressions
+// with Function, and style-src-attr allows inline style attributes such as those set
+// with StyleAttr, which a nonce cannot cover. Pages that use neither can pass a
+// stricter policy to CSP.
+const DefaultCSP = "script-src 'nonce-{nonce}' 'strict-dynamic' 'unsafe-eval'; " +
+	"style-src 'self' 'nonce-{nonce}'; style-src-attr 'unsafe-inline'; " +
+	"object-src 'none'; base-uri 'none'"
+
+type nonceKey struct{}
+
+// WithNonce returns a copy of ctx carrying a CSP nonce. Trees rendered with the
+// context get the nonce on their script and style elements automatically.
+func WithNonce(ctx context.Context, nonce string) context.Context {
+	return context.WithValue(ctx, nonceKey{}, nonce)
+}
+
+// NonceFromContext returns the CSP nonce stored in ctx, or "" if there is none.
+func NonceFromContext(ctx context.Context) string {
+	nonce, _ := ctx.Value(nonceKey{}).(string)
+	return nonce
+}
+
+// NewNonce returns a random base64-encoded nonce suitable for a Content-Security-Policy.
+func NewNonce() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+// CSP returns middleware that generates a nonce for each request, sets the
+// Content-Security-Policy header from policy with every {nonce} replaced, and stores
+// the nonce in the request context for RenderContext to apply. An empty policy
+// means DefaultCSP.
+func CSP(policy string) func(http.Handler) http.Handler {
+	if policy == "" {
+		policy = DefaultCSP
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			nonce := NewNonce()
+			w.Header().Set("Content-Security-Policy", strings.ReplaceAll(policy, "{nonce}", nonce))
+			next.ServeHTTP(w, r.WithContext(WithNonce(r.Context(), nonce)))
+		})
+	}
+}
+
+// needsNonce reports whether a CSP nonce applies to the element: script and style
+// elements, and link elements preloading a script, that do not have one set already.
+func (e Element) needsNonce() bool {
+	if _, ok := e.getAttr("nonce"); ok {
+		return false
+	}
+	switch e.tag {
+	case "script", "style":
+		return true
+	case "link":
+		rel, _ := e.getAttr("rel")
+		for _, r := range strings.Fields(strings.ToLower(rel)) {
+			if r == "modulepreload" {
+				return true
+			}
+			if r == "preload" {
+				as, _ := e.getAttr("as")
+				return strings.EqualFold(as, "script")
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,94 @@
+package htma
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func cspPage() Element {
+	return HTML().AddChild(
+		Head().AddChild(
+			Style().Text("body { margin: 0; }"),
+			Link().RelAttr("preload").Attr("as", "script").HrefAttr("/app.js"),
+			Link().RelAttr("modulepreload").HrefAttr("/mod.js"),
+			Link().RelAttr("stylesheet").HrefAttr("/app.css"),
+			Script().NonceAttr("fixed").Text("init()"),
+		),
+		Body().AddChild(
+			Div().IDAttr("app"),
+			Script().SrcAttr("/datastar.js").TypeAttr("module"),
+		),
+	)
+}
+
+const cspPageWithNonce = `<!DOCTYPE html><html><head>` +
+	`<style nonce="abc">body { margin: 0; }</style>` +
+	`<link rel="preload" as="script" href="/app.js" nonce="abc">` +
+	`<link rel="modulepreload" href="/mod.js" nonce="abc">` +
+	`<link rel="stylesheet" href="/app.css">` +
+	`<script nonce="fixed">init()</script>` +
+	`</head><body><div id="app"></div><script src="/datastar.js" type="module" nonce="abc"></script></body></html>`
+
+func TestNonceOption(t *testing.T) {
+	if got := cspPage().RenderWith(Options{Nonce: "abc"}); got != cspPageWithNonce {
+		t.Errorf("got  %s\nwant %s", got, cspPageWithNonce)
+	}
+	if got := cspPage().Render(); strings.Contains(got, `nonce="abc"`) {
+		t.Errorf("nonce added without being requested: %s", got)
+	}
+}
+
+func TestNonceFromContext(t *testing.T) {
+	var b strings.Builder
+	if err := cspPage().RenderContext(WithNonce(context.Background(), "abc"), &b); err != nil {
+		t.Fatal(err)
+	}
+	if got := b.String(); got != cspPageWithNonce {
+		t.Errorf("got  %s\nwant %s", got, cspPageWithNonce)
+	}
+}
+
+func TestCSPMiddleware(t *testing.T) {
+	var nonces []string
+	handler := CSP("")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		nonces = append(nonces, NonceFromContext(r.Context()))
+		Body().AddChild(Script().Text("go()")).RenderContext(r.Context(), w)
+	}))
+
+	for i := 0; i < 2; i++ {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		nonce := nonces[i]
+		if len(nonce) < 22 {
+			t.Fatalf("nonce %q is too short", nonce)
+		}
+		wantHeader := strings.ReplaceAll(DefaultCSP, "{nonce}", nonce)
+		if got := rec.Header().Get("Content-Security-Policy"); got != wantHeader {
+			t.Errorf("header = %q, want %q", got, wantHeader)
+		}
+		if got, want := rec.Body.String(), `<body><script nonce="`+nonce+`">go()</script></body>`; got != want {
+			t.Errorf("body = %s, want %s", got, want)
+		}
+	}
+	if nonces[0] == nonces[1] {
+		t.Error("the same nonce was used for two requests")
+	}
+}
+
+func TestDefaultCSPAllowsDatastar(t *testing.T) {
+	directives := make(map[string]string)
+	for _, d := range strings.Split(DefaultCSP, ";") {
+		name, value, _ := strings.Cut(strings.TrimSpace(d), " ")
+		directives[name] = value
+	}
+	if !strings.Contains(directives["script-src"], "'unsafe-eval'") {
+		t.Errorf("script-src %q does not allow Datastar expressions", directives["script-src"])
+	}
+	if directives["style-src-attr"] != "'unsafe-inline'" {
+		t.Errorf("style-src-attr = %q, want style attributes allowed", directives["style-src-attr"])
+	}
+}
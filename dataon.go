@@ -0,0 +1,104 @@
+package htma
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Modifier changes how a Datastar event listener set with DataOn behaves.
+type Modifier struct {
+	name string
+	arg  string
+}
+
+// Debounce runs the expression once events have stopped for d.
+func Debounce(d time.Duration) Modifier {
+	return Modifier{"debounce", datastarDuration("Debounce", d)}
+}
+
+// Throttle runs the expression at most once every d.
+func Throttle(d time.Duration) Modifier {
+	return Modifier{"throttle", datastarDuration("Throttle", d)}
+}
+
+// Once removes the listener after the first event.
+func Once() Modifier { return Modifier{name: "once"} }
+
+// Prevent calls preventDefault on the event.
+func Prevent() Modifier { return Modifier{name: "prevent"} }
+
+// Stop calls stopPropagation on the event.
+func Stop() Modifier { return Modifier{name: "stop"} }
+
+// Outside runs the expression for events outside the element, such as clicks that
+// should close a menu.
+func Outside() Modifier { return Modifier{name: "outside"} }
+
+// Window listens for the event on the window rather than the element.
+func Window() Modifier { return Modifier{name: "window"} }
+
+// Passive registers a passive listener, which cannot prevent the default action.
+func Passive() Modifier { return Modifier{name: "passive"} }
+
+// String returns the modifier as written in the attribute name, such as debounce.500ms.
+func (m Modifier) String() string {
+	if m.arg == "" {
+		return m.name
+	}
+	return m.name + "." + m.arg
+}
+
+// datastarDuration formats d as Datastar expects it: whole seconds as 2s, otherwise
+// milliseconds as 500ms. It panics unless d is a positive number of milliseconds.
+func datastarDuration(fn string, d time.Duration) string {
+	if d <= 0 || d%time.Millisecond != 0 {
+		panic(fmt.Sprintf("htma: %s(%v): duration must be a positive number of milliseconds", fn, d))
+	}
+	if d%time.Second == 0 {
+		return strconv.FormatInt(int64(d/time.Second), 10) + "s"
+	}
+	return strconv.FormatInt(int64(d/time.Millisecond), 10) + "ms"
+}
+
+// conflictingModifiers lists modifiers that cannot be combined.
+var conflictingModifiers = [][2]string{
+	{"debounce", "throttle"},
+	{"outside", "window"},
+	{"passive", "prevent"},
+}
+
+// DataOn sets a data-on-<event> attribute running expr when the event fires, with
+// the given modifiers appended in order, as in data-on-keydown__debounce.500ms.
+// It covers any DOM or custom event, including those of md-* components.
+// DataOn panics if event is not a valid event name, a modifier is the zero Modifier
+// or is repeated, or two modifiers conflict: Debounce with Throttle, Outside with
+// Window, or Passive with Prevent.
+func (e Element) DataOn(event, expr string, mods ...Modifier) Element {
+	if event == "" || strings.Contains(event, "__") || ValidateAttrName(event) != nil {
+		panic(fmt.Sprintf("htma: invalid event name %q", event))
+	}
+	seen := make(map[string]bool, len(mods))
+	for _, m := range mods {
+		if m.name == "" {
+			panic(fmt.Sprintf("htma: DataOn(%q): zero Modifier; use a constructor such as Once", event))
+		}
+		if seen[m.name] {
+			panic(fmt.Sprintf("htma: DataOn(%q): duplicate %s modifier", event, m.name))
+		}
+		seen[m.name] = true
+	}
+	for _, c := range conflictingModifiers {
+		if seen[c[0]] && seen[c[1]] {
+			panic(fmt.Sprintf("htma: DataOn(%q): %s cannot be combined with %s", event, c[0], c[1]))
+		}
+	}
+
+	var key strings.Builder
+	key.WriteString("data-on-" + event)
+	for _, m := range mods {
+		key.WriteString("__" + m.String())
+	}
+	return e.Attr(key.String(), expr)
+}
@@ -0,0 +1,55 @@
+package htma
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDataOn(t *testing.T) {
+	tests := []struct {
+		component Element
+		want      string
+	}{
+		{Input().DataOn("input", "@get('/search')", Debounce(500*time.Millisecond)), `<input data-on-input__debounce.500ms="@get(&#39;/search&#39;)">`},
+		{Div().DataOn("keydown", "$open = false", Window(), Throttle(2*time.Second)), `<div data-on-keydown__window__throttle.2s="$open = false"></div>`},
+		{Form().DataOn("submit", Post("/bookings").String(), Prevent(), Once()), `<form data-on-submit__prevent__once="@post(&#39;/bookings&#39;)"></form>`},
+		{Div().DataOn("click", "$menu = false", Outside(), Stop()), `<div data-on-click__outside__stop="$menu = false"></div>`},
+		{Div().DataOn("scroll", "$y = window.scrollY", Passive()), `<div data-on-scroll__passive="$y = window.scrollY"></div>`},
+		{CustomElement("md-switch").DataOn("change", "$on = !$on"), `<md-switch data-on-change="$on = !$on"></md-switch>`},
+	}
+	for _, tt := range tests {
+		if got := tt.component.Render(); got != tt.want {
+			t.Errorf("got  %s\nwant %s", got, tt.want)
+		}
+	}
+}
+
+func TestDataOnPanics(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   func()
+	}{
+		{"debounce and throttle", func() { Div().DataOn("input", "x", Debounce(time.Second), Throttle(time.Second)) }},
+		{"outside and window", func() { Div().DataOn("click", "x", Outside(), Window()) }},
+		{"passive and prevent", func() { Div().DataOn("wheel", "x", Prevent(), Passive()) }},
+		{"duplicate", func() { Div().DataOn("click", "x", Once(), Once()) }},
+		{"duplicate debounce", func() { Div().DataOn("click", "x", Debounce(time.Second), Debounce(time.Millisecond)) }},
+		{"zero modifier", func() { Div().DataOn("click", "x", Modifier{}) }},
+		{"zero modifier after another", func() { Div().DataOn("click", "x", Once(), Modifier{}) }},
+		{"empty event", func() { Div().DataOn("", "x") }},
+		{"event with modifier", func() { Div().DataOn("click__once", "x") }},
+		{"event with space", func() { Div().DataOn("click x", "x") }},
+		{"zero duration", func() { Debounce(0) }},
+		{"sub-millisecond duration", func() { Throttle(time.Microsecond) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("did not panic")
+				}
+			}()
+			tt.fn()
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package htma
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ElementPatch is a datastar-patch-elements operation produced by Diff.
+type ElementPatch struct {
+	Mode     PatchMode
+	Selector string     // target of the patch; empty when Elements are matched by id
+	Elements Renderable // nil for ModeRemove
+}
+
+// Diff compares two versions of an element tree and returns the patches that turn
+// the page showing old into one showing new, to be sent with SSE.SendPatches.
+//
+// Children are matched by id where they have one and by position otherwise. Changed
+// attributes replace the element; added children are appended, removed children are
+// removed, and changes that cannot be expressed that way, such as reordered children,
+// patch the parent's inner HTML. Where several patches would send more markup than
+// one patch of their parent, the parent is patched instead. Elements are compared
+// node by node; other nodes, such as text and components, are rendered once each and
+// compared by their HTML.
+//
+// Elements without an id are targeted with :nth-child() selectors counted from the
+// nearest ancestor with an id, or from the root's tag name if there is none, so the
+// root should have an id or be the only element with its tag on the page. If old or
+// new is not an Element, the result is a single patch of new, matched by id.
+func Diff(old, new Renderable) []ElementPatch {
+	o, ok := old.(Element)
+	n, ok2 := new.(Element)
+	if !ok || !ok2 {
+		if old.Render() == new.Render() {
+			return nil
+		}
+		return []ElementPatch{{Mode: ModeOuter, Elements: new}}
+	}
+	sel := o.tag
+	if id, ok := o.getAttr("id"); ok {
+		sel = idSelector(id)
+	}
+	patches, _, _ := diffElement(o, n, sel)
+	return patches
+}
+
+// diffElement returns the patches turning o, found in the page with sel, into n,
+// along with the approximate bytes they send and the approximate rendered size of n.
+// Each node of n is sized once, so the cost of a diff grows with the size of the tree.
+func diffElement(o, n Element, sel string) (patches []ElementPatch, cost, size int) {
+	if o.tag != n.tag || o.isRoot != n.isRoot || !sameAttrs(o.attrs, n.attrs) {
+		size = nodeSize(n)
+		return []ElementPatch{{Mode: ModeReplace, Selector: sel, Elements: n}}, patchCost(sel, ModeReplace, size), size
+	}
+	textSize := len(escapeInternal(n.text))
+	ok := o.text == n.text
+	var contentSize int
+	if ok {
+		patches, cost, contentSize, ok = diffChildren(flatten(nil, o.children), flatten(nil, n.children), sel)
+	} else {
+		contentSize = nodeSize(Fragment(n.children))
+	}
+	contentSize += textSize
+	size = tagSize(n) + contentSize
+	innerCost := patchCost(sel, ModeInner, contentSize)
+	if !ok || len(patches) > 1 && cost >= innerCost {
+		return []ElementPatch{{Mode: ModeInner, Selector: sel, Elements: n.content()}}, innerCost, size
+	}
+	return patches, cost, size
+}
+
+// diffChildren returns the patches turning the children oc of the element at sel into
+// nc, or false if they cannot be expressed as changes, removals and appends. It also
+// returns the approximate bytes the patches send and the rendered size of nc.
+func diffChildren(oc, nc []Renderable, sel string) (patches []ElementPatch, cost, size int, ok bool) {
+	newIDs := make(map[string]bool)
+	for _, c := range nc {
+		if e, ok := c.(Element); ok {
+			if id, ok := e.getAttr("id"); ok {
+				newIDs[id] = true
+			}
+		}
+	}
+
+	var changes, removals []ElementPatch
+	i, j, pos := 0, 0, 0 // pos counts the old element children, as :nth-child does
+	for i < len(oc) && j < len(nc) {
+		a, aIsEl := oc[i].(Element)
+		b, bIsEl := nc[j].(Element)
+		aID, aHasID := a.getAttr("id")
+		bID, _ := b.getAttr("id")
+		switch {
+		case aIsEl && bIsEl && aID == bID:
+			pos++
+			childSel := sel + " > :nth-child(" + strconv.Itoa(pos) + ")"
+			if aHasID {
+				childSel = idSelector(aID)
+			}
+			p, c, n := diffElement(a, b, childSel)
+			changes = append(changes, p...)
+			cost += c
+			size += n
+			i, j = i+1, j+1
+		case !aIsEl && !bIsEl:
+			html := nc[j].Render()
+			size += len(html)
+			j++
+			if oc[i].Render() != html {
+				return nil, 0, size + nodeSize(Fragment(nc[j:])), false
+			}
+			i++
+		case aIsEl && aHasID && !newIDs[aID]:
+			pos++
+			removal := ElementPatch{Mode: ModeRemove, Selector: idSelector(aID)}
+			removals = append(removals, removal)
+			cost += patchCost(removal.Selector, removal.Mode, 0)
+			i++
+		default:
+			return nil, 0, size + nodeSize(Fragment(nc[j:])), false
+		}
+	}
+	rest := nodeSize(Fragment(nc[j:]))
+	size += rest
+
+	// Old children left over are removed by id, or as one range when some have no id.
+	// The range is counted before the removals above, so it is removed first.
+	var tail []ElementPatch
+	for _, c := range oc[i:] {
+		e, ok := c.(Element)
+		if !ok {
+			return nil, 0, size, false
+		}
+		id, ok := e.getAttr("id")
+		if !ok {
+			tail = []ElementPatch{{Mode: ModeRemove, Selector: sel + " > :nth-child(n+" + strconv.Itoa(pos+1) + ")"}}
+			break
+		}
+		tail = append(tail, ElementPatch{Mode: ModeRemove, Selector: idSelector(id)})
+	}
+	for _, p := range tail {
+		cost += patchCost(p.Selector, p.Mode, 0)
+	}
+	patches = append(changes, tail...)
+	patches = append(patches, removals...)
+	if j < len(nc) {
+		patches = append(patches, ElementPatch{Mode: ModeAppend, Selector: sel, Elements: Fragment(nc[j:])})
+		cost += patchCost(sel, ModeAppend, rest)
+	}
+	return patches, cost, size, true
+}
+
+// content returns the text and children of e.
+func (e Element) content() Fragment {
+	var f Fragment
+	if e.text != "" {
+		f = append(f, Content(e.text))
+	}
+	return append(f, e.children...)
+}
+
+func sameAttrs(a, b []attribute) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].key != b[i].key || a[i].value != b[i].value ||
+			a[i].boolean != b[i].boolean || a[i].trusted != b[i].trusted {
+			return false
+		}
+	}
+	return true
+}
+
+// patchCost approximates the bytes needed to send a patch with elements of the given size.
+func patchCost(sel string, mode PatchMode, size int) int {
+	return len(sel) + len(mode) + size
+}
+
+// nodeSize approximates the rendered size of r. Elements are measured tag by tag and
+// their text as escaped, so only the other nodes in the tree are rendered.
+func nodeSize(r Renderable) int {
+	switch r := r.(type) {
+	case Element:
+		size := tagSize(r) + len(escapeInternal(r.text))
+		for _, c := range r.children {
+			size += nodeSize(c)
+		}
+		return size
+	case Fragment:
+		size := 0
+		for _, c := range r {
+			size += nodeSize(c)
+		}
+		return size
+	}
+	return len(r.Render())
+}
+
+// tagSize returns the rendered size of the start and end tags of e.
+func tagSize(e Element) int {
+	e.text, e.children = "", nil
+	return len(e.Render())
+}
+
+// idSelector returns a selector matching the element with the given id.
+func idSelector(id string) string {
+	plain := id != "" && (isASCIILetter(id[0]) || id[0] == '_')
+	for i := 0; i < len(id) && plain; i++ {
+		c := id[i]
+		plain = isASCIILetter(c) || '0' <= c && c <= '9' || c == '-' || c == '_'
+	}
+	if plain {
+		return "#" + id
+	}
+	return `[id="` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(id) + `"]`
+}
@@ -0,0 +1,215 @@
+package htma
+
+import (
+	"fmt"
+	"io"
+	"testing"
+)
+
+func departureRow(ident, status string) Element {
+	return Li().IDAttr(ident).AddChild(
+		Span().ClassAttr("ident").Text(ident),
+		Span().ClassAttr("status").Text(status),
+	)
+}
+
+func departureBoard(rows ...Element) Element {
+	list := Ul().IDAttr("rows")
+	for _, r := range rows {
+		list = list.AddChild(r)
+	}
+	return Section().IDAttr("board").AddChild(H2().Text("Departures"), list)
+}
+
+func TestDiff(t *testing.T) {
+	ua1, aa3, dl2 := departureRow("UA1", "On time"), departureRow("AA3", "Boarding"), departureRow("DL2", "On time")
+	tests := []struct {
+		name     string
+		old, new Element
+		want     []string
+	}{
+		{"unchanged", departureBoard(ua1, aa3), departureBoard(ua1, aa3), nil},
+		{
+			"status changed",
+			departureBoard(ua1, aa3), departureBoard(ua1, departureRow("AA3", "Delayed")),
+			[]string{"inner #AA3 > :nth-child(2) Delayed"},
+		},
+		{
+			"row added",
+			departureBoard(ua1), departureBoard(ua1, dl2),
+			[]string{`append #rows <li id="DL2"><span class="ident">DL2</span><span class="status">On time</span></li>`},
+		},
+		{
+			"row removed",
+			departureBoard(ua1, aa3, dl2), departureBoard(ua1, dl2),
+			[]string{"remove #AA3 "},
+		},
+		{
+			"row changed and others removed and added",
+			departureBoard(ua1, aa3, dl2), departureBoard(departureRow("UA1", "Gone"), departureRow("BA9", "Late")),
+			[]string{
+				"inner #UA1 > :nth-child(2) Gone",
+				"remove #AA3 ",
+				"remove #DL2 ",
+				`append #rows <li id="BA9"><span class="ident">BA9</span><span class="status">Late</span></li>`,
+			},
+		},
+		{
+			"attribute changed",
+			departureBoard(ua1), departureBoard(ua1.ClassAttr("delayed")),
+			[]string{`replace #UA1 <li id="UA1" class="delayed"><span class="ident">UA1</span><span class="status">On time</span></li>`},
+		},
+		{
+			"URL no longer trusted",
+			Div().AddChild(A().IDAttr("dl").HrefSafeAttr(UncheckedURL("data:text/csv,UA1"))),
+			Div().AddChild(A().IDAttr("dl").HrefAttr("data:text/csv,UA1")),
+			[]string{`replace #dl <a id="dl" href="about:invalid#htma-unsafe-url"></a>`},
+		},
+		{
+			"rows reordered",
+			departureBoard(ua1, aa3), departureBoard(aa3, ua1),
+			[]string{`inner #rows <li id="AA3"><span class="ident">AA3</span><span class="status">Boarding</span></li>` +
+				`<li id="UA1"><span class="ident">UA1</span><span class="status">On time</span></li>`},
+		},
+		{
+			"root without id",
+			Main().AddChild(P().Text("a"), P().Text("b")), Main().AddChild(P().Text("a"), P().Text("c")),
+			[]string{"inner main > :nth-child(2) c"},
+		},
+		{
+			"unnamed children removed",
+			Ol().IDAttr("gates").AddChild(Li().Text("A1"), Li().Text("A2"), Li().Text("A3")), Ol().IDAttr("gates").AddChild(Li().Text("A1")),
+			[]string{"remove #gates > :nth-child(n+2) "},
+		},
+		{
+			"many small changes patch the parent",
+			Ul().IDAttr("l").AddChild(Li().Text("1"), Li().Text("2"), Li().Text("3")), Ul().IDAttr("l").AddChild(Li().Text("4"), Li().Text("5"), Li().Text("6")),
+			[]string{"inner #l <li>4</li><li>5</li><li>6</li>"},
+		},
+		{
+			"id needing escape",
+			Div().AddChild(Div().IDAttr("1a").Text("x")), Div().AddChild(Div().IDAttr("1a").Text("y")),
+			[]string{`inner [id="1a"] y`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patches := Diff(tt.old, tt.new)
+			var got []string
+			for _, p := range patches {
+				elements := ""
+				if p.Elements != nil {
+					elements = p.Elements.Render()
+				}
+				got = append(got, fmt.Sprintf("%s %s %s", p.Mode, p.Selector, elements))
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("got  %q\nwant %q", got, tt.want)
+			}
+			if applied := applyPatches(t, tt.old, patches).Render(); applied != tt.new.Render() {
+				t.Errorf("applying patches gives %s\nwant %s", applied, tt.new.Render())
+			}
+		})
+	}
+}
+
+func TestDiffNonElements(t *testing.T) {
+	patches := Diff(Fragment{Div().IDAttr("a")}, Fragment{Div().IDAttr("a").Text("x")})
+	if len(patches) != 1 || patches[0].Mode != ModeOuter || patches[0].Selector != "" {
+		t.Errorf("got %+v", patches)
+	}
+}
+
+func TestDiffRendersNodesOnce(t *testing.T) {
+	renders := 0
+	fare := Func(func(w io.Writer) error {
+		renders++
+		_, err := io.WriteString(w, "<b>$120</b>")
+		return err
+	})
+	// Each level has a changed child and a new one, so the size of every level is
+	// weighed against the patches below it.
+	tree := func(status string) Element {
+		e := Span().Text(status)
+		for i := 0; i < 8; i++ {
+			e = Div().AddChild(e, P().AddChild(fare))
+			if status == "Delayed" {
+				e = e.AddChild(P().Text("new"))
+			}
+		}
+		return e.IDAttr("board")
+	}
+	old, new := tree("On time"), tree("Delayed")
+	patches := Diff(old, new)
+	if renders != 16 {
+		t.Errorf("components rendered %d times, want 16", renders)
+	}
+	if applied := applyPatches(t, old, patches).Render(); applied != new.Render() {
+		t.Errorf("applying patches gives %s\nwant %s", applied, new.Render())
+	}
+}
+
+// applyPatches applies patches to root as Datastar would apply them to the page.
+func applyPatches(t *testing.T, root Element, patches []ElementPatch) Element {
+	t.Helper()
+	for _, p := range patches {
+		list := mustParseSelector(p.Selector)
+		var targets [][]int
+		for _, n := range indexTree(root) {
+			if list.matches(n) {
+				var path []int
+				for ; n.parent != nil; n = n.parent {
+					path = append([]int{n.index}, path...)
+				}
+				targets = append(targets, path)
+			}
+		}
+		if len(targets) == 0 {
+			t.Fatalf("patch %s %s matches nothing", p.Mode, p.Selector)
+		}
+		if p.Mode != ModeRemove {
+			targets = targets[:1]
+		}
+		for i := len(targets) - 1; i >= 0; i-- {
+			nodes := patchAt(root, targets[i], func(e Element) []Renderable {
+				switch p.Mode {
+				case ModeRemove:
+					return nil
+				case ModeInner:
+					e.text = ""
+					e.children = flatten(nil, []Renderable{p.Elements})
+				case ModeAppend:
+					e.children = append(flatten(nil, e.children), p.Elements)
+				default:
+					return []Renderable{p.Elements}
+				}
+				return []Renderable{e}
+			})
+			root = nodes[0].(Element)
+		}
+	}
+	return root
+}
+
+// patchAt replaces the element at path, given as element child indexes from e,
+// with the nodes returned by fn.
+func patchAt(e Element, path []int, fn func(Element) []Renderable) []Renderable {
+	if len(path) == 0 {
+		return fn(e)
+	}
+	var children []Renderable
+	k := 0
+	for _, c := range flatten(nil, e.children) {
+		if child, ok := c.(Element); ok {
+			if k == path[0] {
+				children = append(children, patchAt(child, path[1:], fn)...)
+				k++
+				continue
+			}
+			k++
+		}
+		children = append(children, c)
+	}
+	e.children = children
+	return []Renderable{e}
+}
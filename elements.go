@@ -0,0 +1,337 @@
+package htma
+
+// elementConstructor is the constructor of an element and its name.
+type elementConstructor struct {
+	name string
+	new  func() Element
+}
+
+// elementConstructors maps tag names to their constructors and the constructor names, so code
+// that builds elements from tag names, such as the parser, gets the same void and root
+// handling, and GenerateGo can call the constructor by name.
+var elementConstructors = map[string]elementConstructor{
+	"a":                       {"A", A},
+	"abbr":                    {"Abbr", Abbr},
+	"address":                 {"Address", Address},
+	"area":                    {"Area", Area},
+	"article":                 {"Article", Article},
+	"aside":                   {"Aside", Aside},
+	"audio":                   {"Audio", Audio},
+	"b":                       {"B", B},
+	"base":                    {"Base", Base},
+	"bdi":                     {"Bdi", Bdi},
+	"bdo":                     {"Bdo", Bdo},
+	"blipta-footer":           {"BliptaFooter", BliptaFooter},
+	"blipta-header":           {"BliptaHeader", BliptaHeader},
+	"blockquote":              {"Blockquote", Blockquote},
+	"body":                    {"Body", Body},
+	"br":                      {"Br", Br},
+	"button":                  {"Button", Button},
+	"canvas":                  {"Canvas", Canvas},
+	"caption":                 {"Caption", Caption},
+	"cite":                    {"Cite", Cite},
+	"code":                    {"Code", Code},
+	"col":                     {"Col", Col},
+	"colgroup":                {"Colgroup", Colgroup},
+	"data":                    {"Data", Data},
+	"datalist":                {"Datalist", Datalist},
+	"dd":                      {"Dd", Dd},
+	"del":                     {"Del", Del},
+	"details":                 {"Details", Details},
+	"dfn":                     {"Dfn", Dfn},
+	"dialog":                  {"Dialog", Dialog},
+	"div":                     {"Div", Div},
+	"dl":                      {"Dl", Dl},
+	"dt":                      {"Dt", Dt},
+	"em":                      {"Em", Em},
+	"embed":                   {"Embed", Embed},
+	"fieldset":                {"Fieldset", Fieldset},
+	"figcaption":              {"Figcaption", Figcaption},
+	"figure":                  {"Figure", Figure},
+	"flight-card":             {"FlightCard", FlightCard},
+	"footer":                  {"Footer", Footer},
+	"form":                    {"Form", Form},
+	"h1":                      {"H1", H1},
+	"h2":                      {"H2", H2},
+	"h3":                      {"H3", H3},
+	"h4":                      {"H4", H4},
+	"h5":                      {"H5", H5},
+	"h6":                      {"H6", H6},
+	"head":                    {"Head", Head},
+	"header":                  {"Header", Header},
+	"hgroup":                  {"Hgroup", Hgroup},
+	"hr":                      {"Hr", Hr},
+	"html":                    {"HTML", HTML},
+	"i":                       {"I", I},
+	"iframe":                  {"Iframe", Iframe},
+	"img":                     {"Img", Img},
+	"input":                   {"Input", Input},
+	"ins":                     {"Ins", Ins},
+	"kbd":                     {"Kbd", Kbd},
+	"label":                   {"Label", Label},
+	"legend":                  {"Legend", Legend},
+	"li":                      {"Li", Li},
+	"link":                    {"Link", Link},
+	"main":                    {"Main", Main},
+	"map":                     {"Map", Map},
+	"mark":                    {"Mark", Mark},
+	"math":                    {"Math", Math},
+	"md-assist-chip":          {"MdAssistChip", MdAssistChip},
+	"md-checkbox":             {"MdCheckbox", MdCheckbox},
+	"md-chip-set":             {"MdChipSet", MdChipSet},
+	"md-circular-progress":    {"MdCircularProgress", MdCircularProgress},
+	"md-dialog":               {"MdDialog", MdDialog},
+	"md-divider":              {"MdDivider", MdDivider},
+	"md-elevated-button":      {"MdElevatedButton", MdElevatedButton},
+	"md-elevated-card":        {"MdElevatedCard", MdElevatedCard},
+	"md-elevation":            {"MdElevation", MdElevation},
+	"md-fab":                  {"MdFab", MdFab},
+	"md-filled-button":        {"MdFilledButton", MdFilledButton},
+	"md-filled-card":          {"MdFilledCard", MdFilledCard},
+	"md-filled-icon-button":   {"MdFilledIconButton", MdFilledIconButton},
+	"md-filled-select":        {"MdFilledSelect", MdFilledSelect},
+	"md-filled-text-field":    {"MdFilledTextField", MdFilledTextField},
+	"md-filter-chip":          {"MdFilterChip", MdFilterChip},
+	"md-focus-ring":           {"MdFocusRing", MdFocusRing},
+	"md-icon":                 {"MdIcon", MdIcon},
+	"md-icon-button":          {"MdIconButton", MdIconButton},
+	"md-input-chip":           {"MdInputChip", MdInputChip},
+	"md-linear-progress":      {"MdLinearProgress", MdLinearProgress},
+	"md-list":                 {"MdList", MdList},
+	"md-list-item":            {"MdListItem", MdListItem},
+	"md-menu":                 {"MdMenu", MdMenu},
+	"md-menu-item":            {"MdMenuItem", MdMenuItem},
+	"md-outlined-button":      {"MdOutlinedButton", MdOutlinedButton},
+	"md-outlined-card":        {"MdOutlinedCard", MdOutlinedCard},
+	"md-outlined-icon-button": {"MdOutlinedIconButton", MdOutlinedIconButton},
+	"md-outlined-select":      {"MdOutlinedSelect", MdOutlinedSelect},
+	"md-outlined-text-field":  {"MdOutlinedTextField", MdOutlinedTextField},
+	"md-primary-tab":          {"MdPrimaryTab", MdPrimaryTab},
+	"md-radio":                {"MdRadio", MdRadio},
+	"md-ripple":               {"MdRipple", MdRipple},
+	"md-secondary-tab":        {"MdSecondaryTab", MdSecondaryTab},
+	"md-slider":               {"MdSlider", MdSlider},
+	"md-snackbar":             {"MdSnackbar", MdSnackbar},
+	"md-sub-menu":             {"MdSubMenu", MdSubMenu},
+	"md-suggestion-chip":      {"MdSuggestionChip", MdSuggestionChip},
+	"md-switch":               {"MdSwitch", MdSwitch},
+	"md-tabs":                 {"MdTabs", MdTabs},
+	"md-text-button":          {"MdTextButton", MdTextButton},
+	"md-tonal-button":         {"MdTonalButton", MdTonalButton},
+	"md-tonal-icon-button":    {"MdTonalIconButton", MdTonalIconButton},
+	"menu":                    {"Menu", Menu},
+	"meta":                    {"Meta", Meta},
+	"meter":                   {"Meter", Meter},
+	"nav":                     {"Nav", Nav},
+	"noscript":                {"Noscript", Noscript},
+	"object":                  {"Object", Object},
+	"ol":                      {"Ol", Ol},
+	"optgroup":                {"Optgroup", Optgroup},
+	"option":                  {"Option", Option},
+	"output":                  {"Output", Output},
+	"p":                       {"P", P},
+	"picture":                 {"Picture", Picture},
+	"pre":                     {"Pre", Pre},
+	"progress":                {"Progress", Progress},
+	"q":                       {"Q", Q},
+	"results-card":            {"ResultsCard", ResultsCard},
+	"rp":                      {"Rp", Rp},
+	"rt":                      {"Rt", Rt},
+	"ruby":                    {"Ruby", Ruby},
+	"s":                       {"S", S},
+	"samp":                    {"Samp", Samp},
+	"script":                  {"Script", Script},
+	"search":                  {"Search", Search},
+	"search-card":             {"SearchCard", SearchCard},
+	"section":                 {"Section", Section},
+	"select":                  {"Select", Select},
+	"slot":                    {"Slot", Slot},
+	"small":                   {"Small", Small},
+	"source":                  {"Source", Source},
+	"span":                    {"Span", Span},
+	"strong":                  {"Strong", Strong},
+	"style":                   {"Style", Style},
+	"sub":                     {"Sub", Sub},
+	"summary":                 {"Summary", Summary},
+	"sup":                     {"Sup", Sup},
+	"svg":                     {"Svg", Svg},
+	"table":                   {"Table", Table},
+	"tbody":                   {"Tbody", Tbody},
+	"td":                      {"Td", Td},
+	"template":                {"Template", Template},
+	"textarea":                {"Textarea", Textarea},
+	"tfoot":                   {"Tfoot", Tfoot},
+	"th":                      {"Th", Th},
+	"thead":                   {"Thead", Thead},
+	"time":                    {"Time", Time},
+	"title":                   {"Title", func() Element { return Title("") }},
+	"tr":                      {"Tr", Tr},
+	"track":                   {"Track", Track},
+	"u":                       {"U", U},
+	"ul":                      {"Ul", Ul},
+	"var":                     {"Var", Var},
+	"video":                   {"Video", Video},
+	"wbr":                     {"Wbr", Wbr},
+}
+
+// attrMethod is the typed method setting one attribute, with its name, so code that
+// works from attribute names, such as GenerateGo, can use the typed helpers.
+type attrMethod[T any] struct {
+	name string
+	set  func(Element, T) Element
+}
+
+// stringAttrMethods maps attribute names to the methods setting them from a string.
+var stringAttrMethods = map[string]attrMethod[string]{
+	"accept":                      {"AcceptAttr", Element.AcceptAttr},
+	"accept-charset":              {"AcceptCharsetAttr", Element.AcceptCharsetAttr},
+	"accesskey":                   {"AccessKeyAttr", Element.AccessKeyAttr},
+	"action":                      {"ActionAttr", Element.ActionAttr},
+	"airline-class":               {"AirlineClassAttr", Element.AirlineClassAttr},
+	"airline-logo-text":           {"AirlineLogoTextAttr", Element.AirlineLogoTextAttr},
+	"airline-name":                {"AirlineNameAttr", Element.AirlineNameAttr},
+	"alt":                         {"AltAttr", Element.AltAttr},
+	"aria-hidden":                 {"AriaHiddenAttr", Element.AriaHiddenAttr},
+	"aria-label":                  {"AriaLabelAttr", Element.AriaLabelAttr},
+	"arrival-time":                {"ArrivalTimeAttr", Element.ArrivalTimeAttr},
+	"autocapitalize":              {"AutocapitalizeAttr", Element.AutocapitalizeAttr},
+	"boarding-time":               {"BoardingTimeAttr", Element.BoardingTimeAttr},
+	"charset":                     {"CharsetAttr", Element.CharsetAttr},
+	"cite":                        {"CiteAttr", Element.CiteAttr},
+	"class":                       {"ClassAttr", Element.ClassAttr},
+	"contenteditable":             {"ContentEditableAttr", Element.ContentEditableAttr},
+	"coords":                      {"CoordsAttr", Element.CoordsAttr},
+	"crossorigin":                 {"CrossOriginAttr", Element.CrossOriginAttr},
+	"data-attr":                   {"DataAttrAttr", Element.DataAttrAttr},
+	"data-bind":                   {"DataBindAttr", Element.DataBindAttr},
+	"data-class":                  {"DataClassAttr", Element.DataClassAttr},
+	"data-computed":               {"DataComputedAttr", Element.DataComputedAttr},
+	"data-effect":                 {"DataEffectAttr", Element.DataEffectAttr},
+	"data-ignore":                 {"DataIgnoreAttr", Element.DataIgnoreAttr},
+	"data-ignore-morph":           {"DataIgnoreMorphAttr", Element.DataIgnoreMorphAttr},
+	"data-indicator":              {"DataIndicatorAttr", Element.DataIndicatorAttr},
+	"data-json-signals":           {"DataJsonSignalsAttr", Element.DataJsonSignalsAttr},
+	"data-on-click":               {"DataOnClickAttr", Element.DataOnClickAttr},
+	"data-on-intersect":           {"DataOnIntersectAttr", Element.DataOnIntersectAttr},
+	"data-on-interval":            {"DataOnIntervalAttr", Element.DataOnIntervalAttr},
+	"data-on-load":                {"DataOnLoadAttr", Element.DataOnLoadAttr},
+	"data-on-signal-patch":        {"DataOnSignalPatchAttr", Element.DataOnSignalPatchAttr},
+	"data-on-signal-patch-filter": {"DataOnSignalPatchFilterAttr", Element.DataOnSignalPatchFilterAttr},
+	"data-preserve-attr":          {"DataPreserveAttrAttr", Element.DataPreserveAttrAttr},
+	"data-ref":                    {"DataRefAttr", Element.DataRefAttr},
+	"data-show":                   {"DataShowAttr", Element.DataShowAttr},
+	"data-signals":                {"DataSignalsAttr", Element.DataSignalsAttr},
+	"data-style":                  {"DataStyleAttr", Element.DataStyleAttr},
+	"data-text":                   {"DataTextAttr", Element.DataTextAttr},
+	"datetime":                    {"DateTimeAttr", Element.DateTimeAttr},
+	"departure-time":              {"DepartureTimeAttr", Element.DepartureTimeAttr},
+	"dest-city":                   {"DestCityAttr", Element.DestCityAttr},
+	"dest-iata":                   {"DestIataAttr", Element.DestIataAttr},
+	"dir":                         {"DirAttr", Element.DirAttr},
+	"download":                    {"DownloadAttr", Element.DownloadAttr},
+	"draggable":                   {"DraggableAttr", Element.DraggableAttr},
+	"enctype":                     {"EncTypeAttr", Element.EncTypeAttr},
+	"enterkeyhint":                {"EnterKeyHintAttr", Element.EnterKeyHintAttr},
+	"flight-number":               {"FlightNumberAttr", Element.FlightNumberAttr},
+	"for":                         {"ForAttr", Element.ForAttr},
+	"form":                        {"FormAttr", Element.FormAttr},
+	"formaction":                  {"FormActionAttr", Element.FormActionAttr},
+	"formenctype":                 {"FormEncTypeAttr", Element.FormEncTypeAttr},
+	"formmethod":                  {"FormMethodAttr", Element.FormMethodAttr},
+	"formtarget":                  {"FormTargetAttr", Element.FormTargetAttr},
+	"gate":                        {"GateAttr", Element.GateAttr},
+	"href":                        {"HrefAttr", Element.HrefAttr},
+	"hreflang":                    {"HrefLangAttr", Element.HrefLangAttr},
+	"http-equiv":                  {"HttpEquivAttr", Element.HttpEquivAttr},
+	"id":                          {"IDAttr", Element.IDAttr},
+	"ident":                       {"IdentAttr", Element.IdentAttr},
+	"inputmode":                   {"InputModeAttr", Element.InputModeAttr},
+	"integrity":                   {"IntegrityAttr", Element.IntegrityAttr},
+	"is":                          {"IsAttr", Element.IsAttr},
+	"itemid":                      {"ItemIDAttr", Element.ItemIDAttr},
+	"itemprop":                    {"ItemPropAttr", Element.ItemPropAttr},
+	"itemref":                     {"ItemRefAttr", Element.ItemRefAttr},
+	"itemtype":                    {"ItemTypeAttr", Element.ItemTypeAttr},
+	"kind":                        {"KindAttr", Element.KindAttr},
+	"label":                       {"LabelAttr", Element.LabelAttr},
+	"lang":                        {"LangAttr", Element.LangAttr},
+	"list":                        {"ListAttr", Element.ListAttr},
+	"max":                         {"MaxAttr", Element.MaxAttr},
+	"media":                       {"MediaAttr", Element.MediaAttr},
+	"method":                      {"MethodAttr", Element.MethodAttr},
+	"min":                         {"MinAttr", Element.MinAttr},
+	"name":                        {"NameAttr", Element.NameAttr},
+	"nonce":                       {"NonceAttr", Element.NonceAttr},
+	"origin-city":                 {"OriginCityAttr", Element.OriginCityAttr},
+	"origin-iata":                 {"OriginIataAttr", Element.OriginIataAttr},
+	"part":                        {"PartAttr", Element.PartAttr},
+	"pattern":                     {"PatternAttr", Element.PatternAttr},
+	"placeholder":                 {"PlaceholderAttr", Element.PlaceholderAttr},
+	"popover":                     {"PopoverAttr", Element.PopoverAttr},
+	"poster":                      {"PosterAttr", Element.PosterAttr},
+	"preload":                     {"PreloadAttr", Element.PreloadAttr},
+	"rel":                         {"RelAttr", Element.RelAttr},
+	"role":                        {"AriaRoleAttr", Element.AriaRoleAttr},
+	"sandbox":                     {"SandboxAttr", Element.SandboxAttr},
+	"scheduled-out":               {"ScheduledOut", Element.ScheduledOut},
+	"scope":                       {"ScopeAttr", Element.ScopeAttr},
+	"shape":                       {"ShapeAttr", Element.ShapeAttr},
+	"sizes":                       {"SizesAttr", Element.SizesAttr},
+	"slot":                        {"SlotAttr", Element.SlotAttr},
+	"spellcheck":                  {"SpellCheckAttr", Element.SpellCheckAttr},
+	"src":                         {"SrcAttr", Element.SrcAttr},
+	"srcdoc":                      {"SrcDocAttr", Element.SrcDocAttr},
+	"srclang":                     {"SrcLangAttr", Element.SrcLangAttr},
+	"srcset":                      {"SrcSetAttr", Element.SrcSetAttr},
+	"status-class":                {"StatusClassAttr", Element.StatusClassAttr},
+	"status-text":                 {"StatusTextAttr", Element.StatusTextAttr},
+	"step":                        {"StepAttr", Element.StepAttr},
+	"target":                      {"TargetAttr", Element.TargetAttr},
+	"title":                       {"TitleAttr", Element.TitleAttr},
+	"translate":                   {"TranslateAttr", Element.TranslateAttr},
+	"type":                        {"TypeAttr", Element.TypeAttr},
+	"usemap":                      {"UseMapAttr", Element.UseMapAttr},
+	"value":                       {"ValueAttr", Element.ValueAttr},
+	"wrap":                        {"WrapAttr", Element.WrapAttr},
+}
+
+// intAttrMethods maps attribute names to the methods setting them from an int.
+var intAttrMethods = map[string]attrMethod[int]{
+	"cols":      {"ColsAttr", Element.ColsAttr},
+	"colspan":   {"ColSpanAttr", Element.ColSpanAttr},
+	"height":    {"HeightAttr", Element.HeightAttr},
+	"maxlength": {"MaxLengthAttr", Element.MaxLengthAttr},
+	"minlength": {"MinLengthAttr", Element.MinLengthAttr},
+	"rows":      {"RowsAttr", Element.RowsAttr},
+	"rowspan":   {"RowSpanAttr", Element.RowSpanAttr},
+	"size":      {"SizeAttr", Element.SizeAttr},
+	"span":      {"SpanAttr", Element.SpanAttr},
+	"start":     {"StartAttr", Element.StartAttr},
+	"tabindex":  {"TabIndexAttr", Element.TabIndexAttr},
+	"width":     {"WidthAttr", Element.WidthAttr},
+}
+
+// boolAttrMethods maps boolean attribute names to the methods switching them on and off.
+var boolAttrMethods = map[string]attrMethod[bool]{
+	"async":          {"AsyncAttr", Element.AsyncAttr},
+	"autofocus":      {"AutofocusAttr", Element.AutofocusAttr},
+	"autoplay":       {"AutoPlayAttr", Element.AutoPlayAttr},
+	"checked":        {"CheckedAttr", Element.CheckedAttr},
+	"controls":       {"ControlsAttr", Element.ControlsAttr},
+	"default":        {"DefaultAttr", Element.DefaultAttr},
+	"defer":          {"DeferAttr", Element.DeferAttr},
+	"disabled":       {"DisabledAttr", Element.DisabledAttr},
+	"formnovalidate": {"FormNoValidateAttr", Element.FormNoValidateAttr},
+	"hidden":         {"HiddenAttr", Element.HiddenAttr},
+	"inert":          {"InertAttr", Element.InertAttr},
+	"itemscope":      {"ItemScopeAttr", Element.ItemScopeAttr},
+	"loop":           {"LoopAttr", Element.LoopAttr},
+	"multiple":       {"MultipleAttr", Element.MultipleAttr},
+	"muted":          {"MutedAttr", Element.MutedAttr},
+	"novalidate":     {"NoValidateAttr", Element.NoValidateAttr},
+	"open":           {"OpenAttr", Element.OpenAttr},
+	"readonly":       {"ReadOnlyAttr", Element.ReadOnlyAttr},
+	"required":       {"RequiredAttr", Element.RequiredAttr},
+	"reversed":       {"ReversedAttr", Element.ReversedAttr},
+	"selected":       {"SelectedAttr", Element.SelectedAttr},
+}
@@ -0,0 +1,118 @@
+package htma
+
+import (
+	"go/ast"
+	goparser "go/parser"
+	"go/token"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// elementAPI lists the element constructors and the single-value Element methods
+// declared in the package source, by name and argument type.
+func elementAPI(t *testing.T) (constructors map[string]bool, methods map[string]string) {
+	t.Helper()
+	files, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	constructors = make(map[string]bool)
+	methods = make(map[string]string)
+	fset := token.NewFileSet()
+	for _, name := range files {
+		if strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		f, err := goparser.ParseFile(fset, name, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, decl := range f.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || !fn.Name.IsExported() || !returnsElement(fn.Type) {
+				continue
+			}
+			params := fn.Type.Params.List
+			switch {
+			case fn.Recv == nil && len(params) == 0:
+				constructors[fn.Name.Name] = true
+			case fn.Recv != nil && len(params) == 1 && len(params[0].Names) == 1:
+				if typ, ok := params[0].Type.(*ast.Ident); ok {
+					methods[fn.Name.Name] = typ.Name
+				}
+			}
+		}
+	}
+	return constructors, methods
+}
+
+func returnsElement(ft *ast.FuncType) bool {
+	if ft.Results == nil || len(ft.Results.List) != 1 {
+		return false
+	}
+	id, ok := ft.Results.List[0].Type.(*ast.Ident)
+	return ok && id.Name == "Element"
+}
+
+func TestElementConstructorsCoverConstructors(t *testing.T) {
+	constructors, _ := elementAPI(t)
+	listed := make(map[string]bool)
+	for tag, ctor := range elementConstructors {
+		listed[ctor.name] = true
+		if got := ctor.new().tag; got != tag {
+			t.Errorf("elementConstructors[%q] builds <%s>", tag, got)
+		}
+		if tag == "title" {
+			continue // Title takes the title text
+		}
+		fn := runtime.FuncForPC(reflect.ValueOf(ctor.new).Pointer()).Name()
+		if !strings.HasSuffix(fn, "."+ctor.name) {
+			t.Errorf("elementConstructors[%q] is named %s but calls %s", tag, ctor.name, fn)
+		}
+	}
+	for name := range constructors {
+		if !listed[name] && name != "CustomElement" {
+			t.Errorf("constructor %s is missing from elementConstructors", name)
+		}
+	}
+}
+
+func TestAttrMethodsCoverMethods(t *testing.T) {
+	// Methods that take a single value but do not set one attribute to it.
+	notSetters := map[string]bool{"Text": true, "RemoveAttr": true}
+
+	_, methods := elementAPI(t)
+	listed := make(map[string]string)
+	for key, m := range stringAttrMethods {
+		listed[m.name] = "string"
+		if got, _ := m.set(Div(), "v").getAttr(key); got != "v" {
+			t.Errorf("stringAttrMethods[%q]: %s sets %q to %q", key, m.name, key, got)
+		}
+	}
+	for key, m := range intAttrMethods {
+		listed[m.name] = "int"
+		if got, _ := m.set(Div(), 7).getAttr(key); got != "7" {
+			t.Errorf("intAttrMethods[%q]: %s sets %q to %q", key, m.name, key, got)
+		}
+	}
+	for key, m := range boolAttrMethods {
+		listed[m.name] = "bool"
+		if e := m.set(Div(), true); len(e.attrs) != 1 || e.attrs[0].key != key || !e.attrs[0].boolean {
+			t.Errorf("boolAttrMethods[%q]: %s(true) sets %+v", key, m.name, e.attrs)
+		}
+		if e := m.set(Div(), true); len(m.set(e, false).attrs) != 0 {
+			t.Errorf("boolAttrMethods[%q]: %s(false) does not remove it", key, m.name)
+		}
+	}
+	for name, typ := range methods {
+		if notSetters[name] || typ != "string" && typ != "int" && typ != "bool" {
+			continue
+		}
+		if listed[name] != typ {
+			t.Errorf("method %s(%s) is missing from the %sAttrMethods table", name, typ, typ)
+		}
+	}
+}
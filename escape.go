@@ -0,0 +1,78 @@
+package htma
+
+import (
+	"regexp"
+	"strings"
+)
+
+// unsafeURL replaces URL attribute values whose scheme is not allowed.
+const unsafeURL = "about:invalid#htma-unsafe-url"
+
+// rawTextTags lists elements whose content the browser does not decode as HTML.
+var rawTextTags = map[string]bool{
+	"script": true,
+	"style":  true,
+}
+
+// urlAttrs lists attributes whose values are navigated to or fetched as URLs.
+var urlAttrs = map[string]bool{
+	"href":       true,
+	"src":        true,
+	"action":     true,
+	"formaction": true,
+	"poster":     true,
+}
+
+// safeURLSchemes lists the schemes allowed in URL attributes. URLs without a scheme
+// (relative paths, fragments, queries) are always allowed.
+var safeURLSchemes = map[string]bool{
+	"http":   true,
+	"https":  true,
+	"mailto": true,
+	"tel":    true,
+}
+
+var (
+	scriptBreakout = regexp.MustCompile(`(?i)<(/script|!--)`)
+	styleBreakout  = regexp.MustCompile(`(?i)</style`)
+)
+
+// escapeRawText prepares the content of a script or style element. The text is left
+// unescaped, since the browser will not decode entities there, but any sequence that
+// would end the element early is broken up with a backslash, which both JavaScript
+// strings and CSS treat as a no-op escape.
+func escapeRawText(tag, s string) string {
+	switch tag {
+	case "script":
+		return scriptBreakout.ReplaceAllString(s, `<\$1`)
+	case "style":
+		return styleBreakout.ReplaceAllStringFunc(s, func(m string) string {
+			return `<\` + m[1:]
+		})
+	}
+	return s
+}
+
+// sanitizeURL returns u unchanged if it is relative or uses an allowed scheme,
+// and unsafeURL otherwise, so values like "javascript:alert(1)" never reach the page.
+func sanitizeURL(u string) string {
+	scheme, ok := urlScheme(u)
+	if !ok || safeURLSchemes[strings.ToLower(scheme)] {
+		return u
+	}
+	return unsafeURL
+}
+
+// urlScheme returns the scheme of u as a browser would see it, and false if u is
+// relative. Browsers ignore leading spaces and control characters and strip tabs and
+// newlines anywhere in a URL, so "java\tscript:" is still javascript.
+func urlScheme(u string) (string, bool) {
+	normalized := strings.TrimLeft(u, "\x00\x01\x02\x03\x04\x05\x06\x07\x08\x09\x0a\x0b\x0c\x0d\x0e\x0f\x10\x11\x12\x13\x14\x15\x16\x17\x18\x19\x1a\x1b\x1c\x1d\x1e\x1f ")
+	normalized = strings.NewReplacer("\t", "", "\n", "", "\r", "").Replace(normalized)
+
+	i := strings.IndexAny(normalized, ":/?#")
+	if i < 0 || normalized[i] != ':' {
+		return "", false
+	}
+	return normalized[:i], true
+}
@@ -0,0 +1,77 @@
+package htma
+
+import "testing"
+
+func TestRawTextEscaping(t *testing.T) {
+	tests := []struct {
+		name      string
+		component Element
+		want      string
+	}{
+		{"script operators", Script().Text("if (a < b && c) { go(\"x\"); }"), `<script>if (a < b && c) { go("x"); }</script>`},
+		{"script breakout", Script().Text(`var s = "</script><img src=x onerror=alert(1)>";`), `<script>var s = "<\/script><img src=x onerror=alert(1)>";</script>`},
+		{"script breakout mixed case", Script().Text(`"</ScRiPt>"`), `<script>"<\/ScRiPt>"</script>`},
+		{"script comment", Script().Text(`"<!--"`), `<script>"<\!--"</script>`},
+		{"script content child", Script().AddChild(Content("a && b</script>")), `<script>a && b<\/script></script>`},
+		{"style selector", Style().Text("a > b, c ~ d { content: '&'; }"), `<style>a > b, c ~ d { content: '&'; }</style>`},
+		{"style breakout", Style().Text("</style><script>alert(1)</script>"), `<style><\/style><script>alert(1)</script></style>`},
+		{"other elements escape", Div().Text("a < b && </div>"), `<div>a &lt; b &amp;&amp; &lt;/div&gt;</div>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.component.Render(); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestURLAttributeSanitizing(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"/flights/UA123", "/flights/UA123"},
+		{"flights?from=SFO&to=EWR", "flights?from=SFO&amp;to=EWR"},
+		{"#top", "#top"},
+		{"https://example.com/a:b", "https://example.com/a:b"},
+		{"HTTP://example.com", "HTTP://example.com"},
+		{"mailto:ops@example.com", "mailto:ops@example.com"},
+		{"tel:+15555550100", "tel:+15555550100"},
+		{"/path/with:colon", "/path/with:colon"},
+		{"javascript:alert(1)", unsafeURL},
+		{"JavaScript:alert(1)", unsafeURL},
+		{" \x01javascript:alert(1)", unsafeURL},
+		{"java\tscript:alert(1)", unsafeURL},
+		{"data:text/html,<script>alert(1)</script>", unsafeURL},
+		{"vbscript:msgbox", unsafeURL},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			want := `<a href="` + tt.want + `"></a>`
+			if got := A().HrefAttr(tt.url).Render(); got != want {
+				t.Errorf("got %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestAllURLAttributesAreSanitized(t *testing.T) {
+	bad := "javascript:alert(1)"
+	tests := []struct {
+		component Element
+		want      string
+	}{
+		{Img().SrcAttr(bad), `<img src="` + unsafeURL + `">`},
+		{Form().ActionAttr(bad), `<form action="` + unsafeURL + `"></form>`},
+		{Button().FormActionAttr(bad), `<button formaction="` + unsafeURL + `"></button>`},
+		{Video().PosterAttr(bad), `<video poster="` + unsafeURL + `"></video>`},
+		{A().Attr("href", bad), `<a href="` + unsafeURL + `"></a>`},
+		{A().TitleAttr(bad), `<a title="javascript:alert(1)"></a>`},
+	}
+	for _, tt := range tests {
+		if got := tt.component.Render(); got != tt.want {
+			t.Errorf("got %s, want %s", got, tt.want)
+		}
+	}
+}
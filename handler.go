@@ -0,0 +1,241 @@
+package htma
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// DefaultBufferSize is the size up to which Handler buffers a page so it can set
+// Content-Length. Larger pages are streamed as they render.
+const DefaultBufferSize = 64 << 10
+
+// HTTPError is an error with the HTTP status code a Handler should respond with.
+type HTTPError struct {
+	Code int
+	Err  error
+}
+
+// StatusError returns an error that makes Handler respond with code. err may be nil.
+func StatusError(code int, err error) error {
+	return &HTTPError{Code: code, Err: err}
+}
+
+func (e *HTTPError) Error() string {
+	if e.Err == nil {
+		return strconv.Itoa(e.Code) + " " + http.StatusText(e.Code)
+	}
+	return strconv.Itoa(e.Code) + " " + http.StatusText(e.Code) + ": " + e.Err.Error()
+}
+
+func (e *HTTPError) Unwrap() error {
+	return e.Err
+}
+
+// statusPage is a page to be sent with a status code other than 200.
+type statusPage struct {
+	code int
+	page Renderable
+}
+
+// Status wraps page so that Handler sends it with the given status code, for example
+// a confirmation page with 201 Created. Rendered elsewhere, it renders as page.
+func Status(code int, page Renderable) Renderable {
+	return statusPage{code: code, page: page}
+}
+
+func (s statusPage) Render() string {
+	return s.page.Render()
+}
+
+func (s statusPage) RenderStream(w io.Writer) error {
+	return s.page.RenderStream(w)
+}
+
+func (s statusPage) RenderContext(ctx context.Context, w io.Writer) error {
+	return renderContext(ctx, w, s.page)
+}
+
+// handlerOptions holds the settings of a Handler.
+type handlerOptions struct {
+	errorPage  func(r *http.Request, code int, err error) Renderable
+	logError   func(r *http.Request, err error)
+	bufferSize int
+}
+
+// HandlerOption configures a Handler.
+type HandlerOption func(*handlerOptions)
+
+// WithErrorPage sets the page rendered when the page function fails. code is the
+// status being sent: the code of an HTTPError, or 500 for any other error.
+func WithErrorPage(page func(r *http.Request, code int, err error) Renderable) HandlerOption {
+	return func(o *handlerOptions) { o.errorPage = page }
+}
+
+// WithErrorLog sets the function called with server errors: page functions failing
+// with a 5xx status, and pages failing to render. The default uses the log package.
+func WithErrorLog(logError func(r *http.Request, err error)) HandlerOption {
+	return func(o *handlerOptions) { o.logError = logError }
+}
+
+// WithBufferSize sets the size up to which pages are buffered to set Content-Length.
+// Zero streams every page.
+func WithBufferSize(n int) HandlerOption {
+	return func(o *handlerOptions) { o.bufferSize = n }
+}
+
+type handler struct {
+	page func(r *http.Request) (Renderable, error)
+	opts handlerOptions
+}
+
+// Handler returns an http.Handler that renders the page returned by fn with the request
+// context, as text/html. Wrap the page with Status to send another status code, or
+// return an HTTPError to fail with one; other errors, and a nil page without an
+// error, send 500. Failures render the error page set with WithErrorPage, or a
+// minimal page naming the status. Render options set on the request context with
+// WithOptions apply to both.
+//
+// Pages up to DefaultBufferSize bytes are buffered, so that Content-Length is set and
+// a render error can still be turned into an error page. Responses to HEAD requests
+// have the same headers as GET responses but no body.
+func Handler(fn func(r *http.Request) (Renderable, error), opts ...HandlerOption) http.Handler {
+	h := &handler{
+		page: fn,
+		opts: handlerOptions{
+			errorPage:  defaultErrorPage,
+			logError:   defaultLogError,
+			bufferSize: DefaultBufferSize,
+		},
+	}
+	for _, opt := range opts {
+		opt(&h.opts)
+	}
+	return h
+}
+
+func defaultErrorPage(r *http.Request, code int, err error) Renderable {
+	text := strconv.Itoa(code) + " " + http.StatusText(code)
+	return HTML().AddChild(
+		Head().AddChild(Title(text)),
+		Body().AddChild(H1().Text(text)),
+	)
+}
+
+func defaultLogError(r *http.Request, err error) {
+	log.Printf("htma: %s %s: %v", r.Method, r.URL.Path, err)
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	page, err := h.page(r)
+	if err != nil {
+		h.fail(w, r, err)
+		return
+	}
+	code := http.StatusOK
+	if s, ok := page.(statusPage); ok {
+		code, page = s.code, s.page
+	}
+	if page == nil {
+		h.fail(w, r, errors.New("htma: page function returned no page and no error"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	resp := &bufferedResponse{w: w, code: code, head: r.Method == http.MethodHead, limit: h.opts.bufferSize}
+	err = renderContext(r.Context(), resp, page)
+	if errors.Is(err, errHeadDone) {
+		return
+	}
+	if err != nil {
+		if r.Context().Err() != nil {
+			return // the client has gone away
+		}
+		if resp.started {
+			// The status has been sent; all that is left is to cut the page short.
+			h.opts.logError(r, err)
+			return
+		}
+		h.fail(w, r, err)
+		return
+	}
+	if err := resp.finish(); err != nil && r.Context().Err() == nil {
+		h.opts.logError(r, err)
+	}
+}
+
+// fail responds with the error page for err.
+func (h *handler) fail(w http.ResponseWriter, r *http.Request, err error) {
+	code := http.StatusInternalServerError
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		code = httpErr.Code
+	}
+	if code >= 500 {
+		h.opts.logError(r, err)
+	}
+
+	var b bytes.Buffer
+	if rerr := renderContext(r.Context(), &b, h.opts.errorPage(r, code, err)); rerr != nil {
+		h.opts.logError(r, rerr)
+		http.Error(w, http.StatusText(code), code)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Length", strconv.Itoa(b.Len()))
+	w.WriteHeader(code)
+	if r.Method != http.MethodHead {
+		w.Write(b.Bytes())
+	}
+}
+
+// errHeadDone stops rendering the body of a HEAD response once it is too large to
+// buffer, as nothing more is needed from it.
+var errHeadDone = errors.New("htma: HEAD response started")
+
+// bufferedResponse holds the start of a page until it exceeds limit bytes, at which
+// point the status is sent and the rest of the page is streamed.
+type bufferedResponse struct {
+	w       http.ResponseWriter
+	code    int
+	head    bool
+	limit   int
+	buf     bytes.Buffer
+	started bool
+}
+
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	if !b.started {
+		if b.buf.Len()+len(p) <= b.limit {
+			return b.buf.Write(p)
+		}
+		b.started = true
+		b.w.WriteHeader(b.code)
+		if b.head {
+			return 0, errHeadDone
+		}
+		if _, err := b.w.Write(b.buf.Bytes()); err != nil {
+			return 0, err
+		}
+		b.buf.Reset()
+	}
+	return b.w.Write(p)
+}
+
+// finish sends a page that fit in the buffer, with its Content-Length.
+func (b *bufferedResponse) finish() error {
+	if b.started {
+		return nil
+	}
+	b.w.Header().Set("Content-Length", strconv.Itoa(b.buf.Len()))
+	b.w.WriteHeader(b.code)
+	if b.head {
+		return nil
+	}
+	_, err := b.w.Write(b.buf.Bytes())
+	return err
+}
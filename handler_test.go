@@ -0,0 +1,102 @@
+package htma
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func flightPage(r *http.Request) (Renderable, error) {
+	switch r.URL.Path {
+	case "/flights/UA1":
+		return Main().AddChild(H1().Text("UA1")), nil
+	case "/flights":
+		return Status(http.StatusCreated, P().Text("booked")), nil
+	case "/missing":
+		return nil, StatusError(http.StatusNotFound, errors.New("no such flight"))
+	case "/broken":
+		return nil, errors.New("database down")
+	case "/nil":
+		return nil, nil
+	case "/nil-status":
+		return Status(http.StatusAccepted, nil), nil
+	case "/render-error":
+		return Div().AddChild(Func(func(w io.Writer) error { return errors.New("boom") })), nil
+	}
+	return Div().Text(strings.Repeat("x", 100)), nil
+}
+
+func TestHandler(t *testing.T) {
+	var logged []string
+	h := Handler(flightPage, WithBufferSize(64), WithErrorLog(func(r *http.Request, err error) {
+		logged = append(logged, err.Error())
+	}))
+	tests := []struct {
+		method, path string
+		code         int
+		body         string
+		length       string
+	}{
+		{"GET", "/flights/UA1", 200, "<main><h1>UA1</h1></main>", "25"},
+		{"HEAD", "/flights/UA1", 200, "", "25"},
+		{"POST", "/flights", 201, "<p>booked</p>", "13"},
+		{"GET", "/missing", 404, "<!DOCTYPE html><html><head><title>404 Not Found</title></head><body><h1>404 Not Found</h1></body></html>", "104"},
+		{"GET", "/broken", 500, "<!DOCTYPE html><html><head><title>500 Internal Server Error</title></head><body><h1>500 Internal Server Error</h1></body></html>", "128"},
+		{"GET", "/nil", 500, "<!DOCTYPE html><html><head><title>500 Internal Server Error</title></head><body><h1>500 Internal Server Error</h1></body></html>", "128"},
+		{"GET", "/nil-status", 500, "<!DOCTYPE html><html><head><title>500 Internal Server Error</title></head><body><h1>500 Internal Server Error</h1></body></html>", "128"},
+		{"GET", "/render-error", 500, "<!DOCTYPE html><html><head><title>500 Internal Server Error</title></head><body><h1>500 Internal Server Error</h1></body></html>", "128"},
+		{"GET", "/large", 200, "<div>" + strings.Repeat("x", 100) + "</div>", ""},
+		{"HEAD", "/large", 200, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+			if rec.Code != tt.code {
+				t.Errorf("status = %d, want %d", rec.Code, tt.code)
+			}
+			if got := rec.Body.String(); got != tt.body {
+				t.Errorf("body = %s, want %s", got, tt.body)
+			}
+			if got := rec.Header().Get("Content-Length"); got != tt.length {
+				t.Errorf("Content-Length = %q, want %q", got, tt.length)
+			}
+			if got := rec.Header().Get("Content-Type"); got != "text/html; charset=utf-8" {
+				t.Errorf("Content-Type = %q", got)
+			}
+		})
+	}
+	noPage := "htma: page function returned no page and no error"
+	want := []string{"database down", noPage, noPage, "htma: rendering div: boom"}
+	if strings.Join(logged, "|") != strings.Join(want, "|") {
+		t.Errorf("logged %q, want %q", logged, want)
+	}
+}
+
+func TestHandlerErrorPage(t *testing.T) {
+	h := Handler(flightPage, WithErrorPage(func(r *http.Request, code int, err error) Renderable {
+		return P().Text(err.Error())
+	}))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/missing", nil))
+	if rec.Code != 404 || rec.Body.String() != "<p>404 Not Found: no such flight</p>" {
+		t.Errorf("got %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestHandlerUsesContextOptions(t *testing.T) {
+	h := Handler(func(r *http.Request) (Renderable, error) {
+		return Main().AddChild(Section(), Section()), nil
+	})
+	withIndent := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.ServeHTTP(w, r.WithContext(WithOptions(r.Context(), Options{Indent: "  "})))
+	})
+	rec := httptest.NewRecorder()
+	withIndent.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if got, want := rec.Body.String(), "<main>\n  <section></section>\n  <section></section>\n</main>"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
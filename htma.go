@@ -2,10 +2,14 @@
 package htma
 
 import (
+	"context"
 	"fmt"
 	"html"
 	"io"
+	"iter"
+	"slices"
 	"strings"
+	"sync/atomic"
 )
 
 // Renderable defines types that can render HTML and SSE.
@@ -14,6 +18,13 @@ type Renderable interface {
 	RenderStream(w io.Writer) error
 }
 
+// ContextRenderer is implemented by nodes that render with a request-scoped context.
+// When a tree is rendered with RenderContext, the context is passed down to every
+// node implementing it, and rendering stops once the context is cancelled.
+type ContextRenderer interface {
+	RenderContext(ctx context.Context, w io.Writer) error
+}
+
 // TextContent represents an escaped plain text node.
 type TextContent struct {
 	Content string
@@ -58,30 +69,141 @@ func RawContent(content string) Raw {
 	return Raw{Content: content}
 }
 
+// Fragment is a list of sibling nodes rendered one after another without a wrapper
+// element, for components that return several siblings such as a Dt and Dd pair or a
+// run of Tr rows. AddChild flattens fragments into the parent's children.
+type Fragment []Renderable
+
+// Render returns the concatenated HTML of the fragment's nodes.
+func (f Fragment) Render() string {
+	var b strings.Builder
+	f.RenderStream(&b)
+	return b.String()
+}
+
+// RenderStream writes the fragment's nodes to a writer.
+func (f Fragment) RenderStream(w io.Writer) error {
+	return f.RenderContext(context.Background(), w)
+}
+
+// RenderContext writes the fragment's nodes to a writer, passing ctx to its components.
+func (f Fragment) RenderContext(ctx context.Context, w io.Writer) error {
+	return f.renderNode(newRenderer(ctx, w, optionsFrom(ctx)))
+}
+
+func (f Fragment) renderNode(r *renderer) error {
+	for i, c := range f {
+		if i > 0 && r.pretty() && isBlockElement(f[i-1]) && isBlockElement(c) {
+			if err := r.newline(); err != nil {
+				return err
+			}
+		}
+		if err := r.renderChildAt(f, i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isFragment(n Renderable) bool {
+	_, ok := n.(Fragment)
+	return ok
+}
+
+// flatten appends nodes to dst, replacing each Fragment with its contents.
+func flatten(dst []Renderable, nodes []Renderable) []Renderable {
+	for _, n := range nodes {
+		if f, ok := n.(Fragment); ok {
+			dst = flatten(dst, f)
+			continue
+		}
+		dst = append(dst, n)
+	}
+	return dst
+}
+
 // Attributable defines types that can set attributes.
 type Attributable interface {
 	Attr(key, value string) Element
 }
 
+// attribute is a single name/value pair on an element.
+// Boolean attributes render as a bare name and carry no value. Trusted attributes
+// were set from a SafeURL and skip URL sanitizing when rendered.
+type attribute struct {
+	key     string
+	value   string
+	boolean bool
+	trusted bool
+}
+
 // Element is the base HTML element, modeling tags, attributes, and children.
+// Attributes are kept in insertion order so the same tree always renders to the same bytes.
+//
+// Element is an immutable value: every method returns a new Element and never modifies
+// the receiver, so a partially built element can be reused as a prototype for others.
+// The attrs and children slices are shared between copies and must only be grown
+// through appendShared or replaced by a fresh copy, never written in place.
 type Element struct {
-	tag      string
-	attrs    map[string]string
-	children []Renderable
-	text     string
-	isVoid   bool
-	isRoot   bool // Indicates if this is the root <html> element
+	tag           string
+	attrs         []attribute
+	attrsClaim    *arrayClaim[attribute]
+	children      []Renderable
+	childrenClaim *arrayClaim[Renderable]
+	text          string
+	isVoid        bool
+	isRoot        bool // Indicates if this is the root <html> element
+}
+
+// arrayClaim records how much of a backing array shared between Element copies is in
+// use. A copy whose slice ends where the used part ends may append in place, because
+// no other copy can see the slots after it; any other copy must append to a new array.
+// This keeps building an element by chaining linear while derived elements never alias.
+type arrayClaim[T any] struct {
+	first *T // first slot of the claimed array
+	used  atomic.Int64
+}
+
+// appendShared appends items to s, whose backing array is described by c, and returns
+// the new slice with the claim on its backing array. c may be nil or describe another
+// array, in which case s is treated as shared.
+func appendShared[T any](s []T, c *arrayClaim[T], items ...T) ([]T, *arrayClaim[T]) {
+	if len(items) == 0 {
+		return s, c
+	}
+	n := len(s)
+	if c != nil && n+len(items) <= cap(s) && c.first == &s[:1][0] &&
+		c.used.CompareAndSwap(int64(n), int64(n+len(items))) {
+		return append(s, items...), c
+	}
+	grown := make([]T, 0, max(4, 2*n, n+len(items)))
+	grown = append(append(grown, s...), items...)
+	c = &arrayClaim[T]{first: &grown[0]}
+	c.used.Store(int64(len(grown)))
+	return grown, c
 }
 
 // newElement creates a generic element (internal).
 func newElement(tag string, isVoid bool) Element {
 	return Element{
 		tag:    tag,
-		attrs:  make(map[string]string),
 		isVoid: isVoid,
 	}
 }
 
+// CustomElement creates an element for a tag without a dedicated constructor,
+// such as a custom element from a third-party component library.
+// It panics if tag fails ValidateTagName; custom element names must contain a hyphen.
+func CustomElement(tag string) Element {
+	if ctor, ok := elementConstructors[tag]; ok {
+		return ctor.new()
+	}
+	if err := ValidateTagName(tag); err != nil {
+		panic(err.Error())
+	}
+	return newElement(tag, false)
+}
+
 // Constructors for HTML Elements (alphabetical order)
 func A() Element {
 	return newElement("a", false)
@@ -276,7 +398,7 @@ func Hr() Element {
 }
 
 func HTML() Element {
-	return Element{tag: "html", attrs: make(map[string]string), isRoot: true}
+	return Element{tag: "html", isRoot: true}
 }
 
 func I() Element {
@@ -540,14 +662,22 @@ func Wbr() Element {
 }
 
 // Element Methods (Chainable)
+
+// AddChild appends child nodes. Fragments are flattened, so their nodes become
+// direct children of the element.
 func (e Element) AddChild(children ...Renderable) Element {
 	if e.isVoid {
 		panic(fmt.Sprintf("cannot add children to void element: <%s>", e.tag))
 	}
-	e.children = append(e.children, children...)
+	if slices.ContainsFunc(children, isFragment) {
+		children = flatten(nil, children)
+	}
+	e.children, e.childrenClaim = appendShared(e.children, e.childrenClaim, children...)
 	return e
 }
 
+// Text sets the element's text content. It is HTML-escaped when rendered, except inside
+// script and style, where it is written as raw code guarded against closing the element.
 func (e Element) Text(text string) Element {
 	if e.isVoid {
 		panic(fmt.Sprintf("cannot add text to void element: <%s>", e.tag))
@@ -560,8 +690,7 @@ func (e Element) IDAttr(id string) Element {
 	if strings.ContainsAny(id, " \t\n") {
 		panic("invalid ID: " + id)
 	}
-	e.attrs["id"] = id
-	return e
+	return e.setAttr("id", id)
 }
 
 func (e Element) ClassAttr(class string) Element {
@@ -575,8 +704,8 @@ func (e Element) ClassAttr(class string) Element {
 		}
 	}
 	combined := strings.Join(classes, " ")
-	e.attrs["class"] = appendClassInternal(e.attrs["class"], combined)
-	return e
+	existing, _ := e.getAttr("class")
+	return e.setAttr("class", appendClassInternal(existing, combined))
 }
 
 func (e Element) Classes(classes ...string) Element {
@@ -586,18 +715,69 @@ func (e Element) Classes(classes ...string) Element {
 	return e
 }
 
+// ClassIf adds class when cond is true and returns the element unchanged otherwise.
+func (e Element) ClassIf(cond bool, class string) Element {
+	if !cond {
+		return e
+	}
+	return e.ClassAttr(class)
+}
+
 func (e Element) StyleAttr(key, value string) Element {
-	current := e.attrs["style"]
+	current, _ := e.getAttr("style")
 	if current != "" {
 		current += "; "
 	}
-	e.attrs["style"] = current + fmt.Sprintf("%s: %s", key, value)
-	return e
+	return e.setAttr("style", current+fmt.Sprintf("%s: %s", key, value))
 }
 
+// Attr sets an attribute. The name is checked when the element is rendered: an
+// invalid name makes rendering fail unless Options.Lenient is set, in which case the
+// attribute is dropped. Check names that come from user input with ValidateAttrName.
 func (e Element) Attr(key, value string) Element {
-	e.attrs[key] = value
-	return e
+	return e.setAttr(key, value)
+}
+
+// AttrIf sets an attribute when cond is true and returns the element unchanged otherwise.
+func (e Element) AttrIf(cond bool, key, value string) Element {
+	if !cond {
+		return e
+	}
+	return e.Attr(key, value)
+}
+
+// BoolAttr sets a boolean attribute such as disabled or checked when on is true,
+// rendering it as a bare name, and removes it when on is false. Invalid names are
+// handled at render time as for Attr.
+func (e Element) BoolAttr(name string, on bool) Element {
+	if !on {
+		return e.removeAttr(name)
+	}
+	return e.setAttribute(attribute{key: name, boolean: true})
+}
+
+// RemoveAttr returns a copy of the element without the named attribute.
+func (e Element) RemoveAttr(key string) Element {
+	return e.removeAttr(key)
+}
+
+// RemoveClass returns a copy of the element without the given class names.
+// The class attribute is removed entirely once no classes remain.
+func (e Element) RemoveClass(classes ...string) Element {
+	existing, ok := e.getAttr("class")
+	if !ok {
+		return e
+	}
+	var kept []string
+	for _, cls := range strings.Fields(existing) {
+		if !slices.Contains(classes, cls) {
+			kept = append(kept, cls)
+		}
+	}
+	if len(kept) == 0 {
+		return e.removeAttr("class")
+	}
+	return e.setAttr("class", strings.Join(kept, " "))
 }
 
 // Global Attribute Methods
@@ -609,8 +789,8 @@ func (e Element) AutocapitalizeAttr(value string) Element {
 	return e.Attr("autocapitalize", value)
 }
 
-func (e Element) AutofocusAttr() Element {
-	return e.Attr("autofocus", "")
+func (e Element) AutofocusAttr(on bool) Element {
+	return e.BoolAttr("autofocus", on)
 }
 
 func (e Element) ContentEditableAttr(value string) Element {
@@ -629,12 +809,12 @@ func (e Element) EnterKeyHintAttr(value string) Element {
 	return e.Attr("enterkeyhint", value)
 }
 
-func (e Element) HiddenAttr() Element {
-	return e.Attr("hidden", "")
+func (e Element) HiddenAttr(on bool) Element {
+	return e.BoolAttr("hidden", on)
 }
 
-func (e Element) InertAttr() Element {
-	return e.Attr("inert", "")
+func (e Element) InertAttr(on bool) Element {
+	return e.BoolAttr("inert", on)
 }
 
 func (e Element) InputModeAttr(value string) Element {
@@ -657,8 +837,8 @@ func (e Element) ItemRefAttr(value string) Element {
 	return e.Attr("itemref", value)
 }
 
-func (e Element) ItemScopeAttr() Element {
-	return e.Attr("itemscope", "")
+func (e Element) ItemScopeAttr(on bool) Element {
+	return e.BoolAttr("itemscope", on)
 }
 
 func (e Element) ItemTypeAttr(value string) Element {
@@ -821,20 +1001,20 @@ func (e Element) AltAttr(alt string) Element {
 	return e.Attr("alt", alt)
 }
 
-func (e Element) AsyncAttr() Element {
-	return e.Attr("async", "")
+func (e Element) AsyncAttr(on bool) Element {
+	return e.BoolAttr("async", on)
 }
 
-func (e Element) AutoPlayAttr() Element {
-	return e.Attr("autoplay", "")
+func (e Element) AutoPlayAttr(on bool) Element {
+	return e.BoolAttr("autoplay", on)
 }
 
 func (e Element) CharsetAttr(value string) Element {
 	return e.Attr("charset", value)
 }
 
-func (e Element) CheckedAttr() Element {
-	return e.Attr("checked", "")
+func (e Element) CheckedAttr(on bool) Element {
+	return e.BoolAttr("checked", on)
 }
 
 func (e Element) CiteAttr(value string) Element {
@@ -849,8 +1029,8 @@ func (e Element) ColSpanAttr(span int) Element {
 	return e.Attr("colspan", fmt.Sprint(span))
 }
 
-func (e Element) ControlsAttr() Element {
-	return e.Attr("controls", "")
+func (e Element) ControlsAttr(on bool) Element {
+	return e.BoolAttr("controls", on)
 }
 
 func (e Element) CoordsAttr(value string) Element {
@@ -865,16 +1045,16 @@ func (e Element) DateTimeAttr(value string) Element {
 	return e.Attr("datetime", value)
 }
 
-func (e Element) DefaultAttr() Element {
-	return e.Attr("default", "")
+func (e Element) DefaultAttr(on bool) Element {
+	return e.BoolAttr("default", on)
 }
 
-func (e Element) DeferAttr() Element {
-	return e.Attr("defer", "")
+func (e Element) DeferAttr(on bool) Element {
+	return e.BoolAttr("defer", on)
 }
 
-func (e Element) DisabledAttr() Element {
-	return e.Attr("disabled", "")
+func (e Element) DisabledAttr(on bool) Element {
+	return e.BoolAttr("disabled", on)
 }
 
 func (e Element) DownloadAttr(value string) Element {
@@ -905,8 +1085,8 @@ func (e Element) FormMethodAttr(value string) Element {
 	return e.Attr("formmethod", value)
 }
 
-func (e Element) FormNoValidateAttr() Element {
-	return e.Attr("formnovalidate", "")
+func (e Element) FormNoValidateAttr(on bool) Element {
+	return e.BoolAttr("formnovalidate", on)
 }
 
 func (e Element) FormTargetAttr(value string) Element {
@@ -945,8 +1125,8 @@ func (e Element) ListAttr(value string) Element {
 	return e.Attr("list", value)
 }
 
-func (e Element) LoopAttr() Element {
-	return e.Attr("loop", "")
+func (e Element) LoopAttr(on bool) Element {
+	return e.BoolAttr("loop", on)
 }
 
 func (e Element) MaxAttr(value string) Element {
@@ -973,24 +1153,24 @@ func (e Element) MinLengthAttr(length int) Element {
 	return e.Attr("minlength", fmt.Sprint(length))
 }
 
-func (e Element) MultipleAttr() Element {
-	return e.Attr("multiple", "")
+func (e Element) MultipleAttr(on bool) Element {
+	return e.BoolAttr("multiple", on)
 }
 
-func (e Element) MutedAttr() Element {
-	return e.Attr("muted", "")
+func (e Element) MutedAttr(on bool) Element {
+	return e.BoolAttr("muted", on)
 }
 
 func (e Element) NameAttr(value string) Element {
 	return e.Attr("name", value)
 }
 
-func (e Element) NoValidateAttr() Element {
-	return e.Attr("novalidate", "")
+func (e Element) NoValidateAttr(on bool) Element {
+	return e.BoolAttr("novalidate", on)
 }
 
-func (e Element) OpenAttr() Element {
-	return e.Attr("open", "")
+func (e Element) OpenAttr(on bool) Element {
+	return e.BoolAttr("open", on)
 }
 
 func (e Element) PatternAttr(value string) Element {
@@ -1009,20 +1189,20 @@ func (e Element) PreloadAttr(value string) Element {
 	return e.Attr("preload", value)
 }
 
-func (e Element) ReadOnlyAttr() Element {
-	return e.Attr("readonly", "")
+func (e Element) ReadOnlyAttr(on bool) Element {
+	return e.BoolAttr("readonly", on)
 }
 
 func (e Element) RelAttr(value string) Element {
 	return e.Attr("rel", value)
 }
 
-func (e Element) RequiredAttr() Element {
-	return e.Attr("required", "")
+func (e Element) RequiredAttr(on bool) Element {
+	return e.BoolAttr("required", on)
 }
 
-func (e Element) ReversedAttr() Element {
-	return e.Attr("reversed", "")
+func (e Element) ReversedAttr(on bool) Element {
+	return e.BoolAttr("reversed", on)
 }
 
 func (e Element) RowsAttr(rows int) Element {
@@ -1041,8 +1221,8 @@ func (e Element) ScopeAttr(value string) Element {
 	return e.Attr("scope", value)
 }
 
-func (e Element) SelectedAttr() Element {
-	return e.Attr("selected", "")
+func (e Element) SelectedAttr(on bool) Element {
+	return e.BoolAttr("selected", on)
 }
 
 func (e Element) ShapeAttr(value string) Element {
@@ -1112,11 +1292,60 @@ func (e Element) WrapAttr(value string) Element {
 // Attributable Implementation
 func (e Element) AddAttribute(key, value string) Element {
 	// Deprecated: Use Attr instead.
-	e.attrs[key] = value
-	return e
+	return e.setAttr(key, value)
+}
+
+// Inspection Methods
+
+// Tag returns the element's tag name.
+func (e Element) Tag() string {
+	return e.tag
+}
+
+// GetAttr returns the value of the named attribute and whether it is set.
+// Boolean attributes report an empty value.
+func (e Element) GetAttr(key string) (string, bool) {
+	return e.getAttr(key)
+}
+
+// HasClass reports whether class is one of the element's class names.
+func (e Element) HasClass(class string) bool {
+	existing, _ := e.getAttr("class")
+	return slices.Contains(strings.Fields(existing), class)
+}
+
+// Attrs returns an iterator over the element's attributes in render order.
+// Boolean attributes are yielded with an empty value.
+func (e Element) Attrs() iter.Seq2[string, string] {
+	return func(yield func(string, string) bool) {
+		for _, a := range e.attrs {
+			if !yield(a.key, a.value) {
+				return
+			}
+		}
+	}
+}
+
+// Children returns a copy of the element's child nodes.
+func (e Element) Children() []Renderable {
+	return slices.Clone(e.children)
+}
+
+// TextValue returns the text set with Text, before escaping.
+func (e Element) TextValue() string {
+	return e.text
+}
+
+// IsVoid reports whether the element is a void element, such as br or img,
+// which has no end tag and cannot have children.
+func (e Element) IsVoid() bool {
+	return e.isVoid
 }
 
 // Render Methods for Element
+
+// Render returns the element's HTML. Any render error is discarded; use RenderString
+// when the tree contains nodes that can fail, such as Func or ComponentFunc.
 func (e Element) Render() string {
 	var b strings.Builder
 	e.RenderStream(&b)
@@ -1124,54 +1353,232 @@ func (e Element) Render() string {
 }
 
 func (e Element) RenderStream(w io.Writer) error {
+	return e.RenderStreamWith(w, Options{})
+}
+
+// RenderWith returns the HTML for the element rendered with the given options.
+func (e Element) RenderWith(opts Options) string {
+	var b strings.Builder
+	e.RenderStreamWith(&b, opts)
+	return b.String()
+}
+
+// RenderStreamWith writes the element to w using the given options.
+// Errors from child nodes are returned as a *RenderError giving the path to the failed node.
+func (e Element) RenderStreamWith(w io.Writer, opts Options) error {
+	return e.renderRoot(newRenderer(context.Background(), w, opts))
+}
+
+// RenderContext writes the element to w, passing ctx to the components in the tree.
+// Rendering stops with the context's error once ctx is cancelled, for example when
+// the client of a long streaming response disconnects.
+func (e Element) RenderContext(ctx context.Context, w io.Writer) error {
+	return e.renderRoot(newRenderer(ctx, w, optionsFrom(ctx)))
+}
+
+// renderRoot renders e as the top of a tree, starting the error path with its tag.
+func (e Element) renderRoot(r *renderer) error {
+	if err := e.renderNode(r); err != nil {
+		return wrapRenderError(err, e.tag)
+	}
+	return nil
+}
+
+func (e Element) renderNode(r *renderer) error {
+	// Names are checked before anything is written, so a failed render leaves no
+	// partial start tag behind.
+	if err := ValidateTagName(e.tag); err != nil {
+		if !r.opts.Lenient {
+			return err
+		}
+		return e.renderInlineContent(r)
+	}
+	if !r.opts.Lenient {
+		for _, a := range e.attrs {
+			if err := ValidateAttrName(a.key); err != nil {
+				return err
+			}
+		}
+	}
 	if e.isRoot {
-		if _, err := io.WriteString(w, "<!DOCTYPE html>"); err != nil {
+		if err := r.write("<!DOCTYPE html>"); err != nil {
 			return err
 		}
+		if r.pretty() {
+			if err := r.write("\n"); err != nil {
+				return err
+			}
+		}
 	}
-	if _, err := io.WriteString(w, "<"); err != nil {
+	if err := r.write("<"); err != nil {
 		return err
 	}
-	if _, err := io.WriteString(w, e.tag); err != nil {
+	if err := r.write(e.tag); err != nil {
 		return err
 	}
-	for k, v := range e.attrs {
-		if _, err := fmt.Fprintf(w, ` %s="%s"`, k, escapeInternal(v)); err != nil {
+	for _, a := range e.attrs {
+		if r.opts.Lenient && ValidateAttrName(a.key) != nil {
+			continue
+		}
+		if a.boolean {
+			if _, err := fmt.Fprintf(r.w, " %s", a.key); err != nil {
+				return err
+			}
+			continue
+		}
+		value := a.value
+		if urlAttrs[a.key] && !a.trusted {
+			value = sanitizeURL(value)
+		}
+		if _, err := fmt.Fprintf(r.w, ` %s="%s"`, a.key, escapeInternal(value)); err != nil {
 			return err
 		}
 	}
-	if e.isVoid {
-		if _, err := io.WriteString(w, ">"); err != nil {
+	if r.opts.Nonce != "" && e.needsNonce() {
+		if _, err := fmt.Fprintf(r.w, ` nonce="%s"`, escapeInternal(r.opts.Nonce)); err != nil {
 			return err
 		}
+	}
+	if err := r.write(">"); err != nil {
+		return err
+	}
+	if e.isVoid {
 		return nil
 	}
-	if _, err := io.WriteString(w, ">"); err != nil {
+	if r.pretty() && e.hasBlockContent() {
+		if err := e.renderIndentedChildren(r); err != nil {
+			return err
+		}
+	} else if err := e.renderInlineContent(r); err != nil {
 		return err
 	}
+	if err := r.write("</"); err != nil {
+		return err
+	}
+	if err := r.write(e.tag); err != nil {
+		return err
+	}
+	return r.write(">")
+}
+
+// renderInlineContent writes the text and children without adding any whitespace.
+func (e Element) renderInlineContent(r *renderer) error {
+	indent := r.opts.Indent
+	r.opts.Indent = ""
+	defer func() { r.opts.Indent = indent }()
+
+	if rawTextTags[e.tag] {
+		return e.renderRawText(r)
+	}
 	if e.text != "" {
-		if _, err := io.WriteString(w, escapeInternal(e.text)); err != nil {
+		if err := r.write(escapeInternal(e.text)); err != nil {
 			return err
 		}
 	}
-	for _, c := range e.children {
-		if err := c.RenderStream(w); err != nil {
+	for i := range e.children {
+		if err := r.renderChildAt(e.children, i); err != nil {
 			return err
 		}
 	}
-	if _, err := io.WriteString(w, "</"); err != nil {
-		return err
-	}
-	if _, err := io.WriteString(w, e.tag); err != nil {
+	return nil
+}
+
+// renderRawText writes the content of a script or style element, where HTML escaping
+// would corrupt the code. Text nodes are written as raw text guarded against breakout.
+func (e Element) renderRawText(r *renderer) error {
+	if err := r.write(escapeRawText(e.tag, e.text)); err != nil {
 		return err
 	}
-	if _, err := io.WriteString(w, ">"); err != nil {
-		return err
+	for i, c := range e.children {
+		if t, ok := c.(TextContent); ok {
+			if err := r.write(escapeRawText(e.tag, t.Content)); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := r.renderChildAt(e.children, i); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
+// renderIndentedChildren writes each child on its own line, one level deeper than e.
+func (e Element) renderIndentedChildren(r *renderer) error {
+	r.depth++
+	for i := range e.children {
+		if err := r.newline(); err != nil {
+			return err
+		}
+		if err := r.renderChildAt(e.children, i); err != nil {
+			return err
+		}
+	}
+	r.depth--
+	return r.newline()
+}
+
+// hasBlockContent reports whether whitespace may be inserted between the element's
+// children without changing how the page renders: there is no text, the element does
+// not preserve whitespace, and every child is a block-level element.
+func (e Element) hasBlockContent() bool {
+	if e.text != "" || len(e.children) == 0 || whitespaceSensitiveTags[e.tag] {
+		return false
+	}
+	for _, c := range e.children {
+		if !isBlockElement(c) {
+			return false
+		}
+	}
+	return true
+}
+
 // Helper Functions
+
+// getAttr returns the value of the named attribute and whether it is set.
+func (e Element) getAttr(key string) (string, bool) {
+	for _, a := range e.attrs {
+		if a.key == key {
+			return a.value, true
+		}
+	}
+	return "", false
+}
+
+// setAttr sets an attribute, keeping its original position if it is already present.
+func (e Element) setAttr(key, value string) Element {
+	return e.setAttribute(attribute{key: key, value: value})
+}
+
+// setAttribute stores attr, replacing any attribute with the same key in place.
+// The attribute slice is copied rather than modified so earlier copies of e are unaffected.
+func (e Element) setAttribute(attr attribute) Element {
+	for i, a := range e.attrs {
+		if a.key == attr.key {
+			attrs := make([]attribute, len(e.attrs))
+			copy(attrs, e.attrs)
+			attrs[i] = attr
+			e.attrs = attrs
+			return e
+		}
+	}
+	e.attrs, e.attrsClaim = appendShared(e.attrs, e.attrsClaim, attr)
+	return e
+}
+
+// removeAttr returns e without the named attribute.
+func (e Element) removeAttr(key string) Element {
+	for i, a := range e.attrs {
+		if a.key == key {
+			attrs := make([]attribute, 0, len(e.attrs)-1)
+			attrs = append(attrs, e.attrs[:i]...)
+			e.attrs = append(attrs, e.attrs[i+1:]...)
+			return e
+		}
+	}
+	return e
+}
+
 func appendClassInternal(existing, newClass string) string {
 	if existing == "" {
 		return newClass
@@ -1,11 +1,14 @@
 package htma
 
 import (
+	"context"
 	"flag"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
 )
 
@@ -107,3 +110,351 @@ func TestMixedContentRendering(t *testing.T) {
 		t.Errorf("rendered HTML does not match golden file.\nGot:\n%s\n\nWant:\n%s", renderedHTML.String(), string(expectedHTML))
 	}
 }
+
+// checkGolden compares rendered output with testdata/<name>.golden, rewriting the file when -update is set.
+func checkGolden(t *testing.T, name, got string) {
+	t.Helper()
+	goldenFile := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(goldenFile), 0755); err != nil {
+			t.Fatalf("failed to create testdata directory: %v", err)
+		}
+		if err := os.WriteFile(goldenFile, []byte(got), 0644); err != nil {
+			t.Fatalf("failed to update golden file: %v", err)
+		}
+	}
+	want, err := os.ReadFile(goldenFile)
+	if err != nil {
+		t.Fatalf("failed to read golden file: %v", err)
+	}
+	if got != string(want) {
+		t.Errorf("rendered HTML does not match golden file.\nGot:\n%s\n\nWant:\n%s", got, string(want))
+	}
+}
+
+func TestMultiAttributeRendering(t *testing.T) {
+	component := Form().IDAttr("search").ActionAttr("/flights").MethodAttr("get").ClassAttr("card").AddChild(
+		Input().TypeAttr("text").NameAttr("ident").PlaceholderAttr("UA123").ClassAttr("field").StyleAttr("width", "10em"),
+		A().HrefAttr("/help").TargetAttr("_blank").RelAttr("noopener").DataOnClickAttr("@get('/help')").Text("Help"),
+		FlightCard().IdentAttr("UA123").FlightNumberAttr("123").OriginIataAttr("SFO").DestIataAttr("EWR").ClassAttr("live"),
+	)
+
+	var renderedHTML strings.Builder
+	if err := component.RenderStream(&renderedHTML); err != nil {
+		t.Fatalf("failed to render component: %v", err)
+	}
+	checkGolden(t, "TestMultiAttributeRendering", renderedHTML.String())
+}
+
+func TestAttributeOrderIsStable(t *testing.T) {
+	component := Div().IDAttr("a").ClassAttr("x").TitleAttr("t").LangAttr("en").DataAttr("k", "v").AriaLabelAttr("label")
+	first := component.Render()
+	for i := 0; i < 50; i++ {
+		if got := component.Render(); got != first {
+			t.Fatalf("render %d differs:\n%s\n%s", i, got, first)
+		}
+	}
+}
+
+func TestAttributeOverwriteKeepsPosition(t *testing.T) {
+	got := Div().IDAttr("a").TitleAttr("old").ClassAttr("x").TitleAttr("new").ClassAttr("y").Render()
+	want := `<div id="a" title="new" class="x y"></div>`
+	if got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestDerivedElementsDoNotShareAttributes(t *testing.T) {
+	base := Div().ClassAttr("card")
+	a := base.IDAttr("a")
+	b := base.IDAttr("b")
+
+	if got, want := base.Render(), `<div class="card"></div>`; got != want {
+		t.Errorf("base changed: got %s, want %s", got, want)
+	}
+	if got, want := a.Render(), `<div class="card" id="a"></div>`; got != want {
+		t.Errorf("a: got %s, want %s", got, want)
+	}
+	if got, want := b.Render(), `<div class="card" id="b"></div>`; got != want {
+		t.Errorf("b: got %s, want %s", got, want)
+	}
+}
+
+func TestDerivedElementsDoNotShareOverwrittenAttributes(t *testing.T) {
+	base := Input().TypeAttr("text").NameAttr("ident")
+	a := base.TypeAttr("search")
+	b := base.ClassAttr("wide")
+
+	if got, want := base.Render(), `<input type="text" name="ident">`; got != want {
+		t.Errorf("base changed: got %s, want %s", got, want)
+	}
+	if got, want := a.Render(), `<input type="search" name="ident">`; got != want {
+		t.Errorf("a: got %s, want %s", got, want)
+	}
+	if got, want := b.Render(), `<input type="text" name="ident" class="wide">`; got != want {
+		t.Errorf("b: got %s, want %s", got, want)
+	}
+}
+
+func TestDerivedElementsDoNotShareChildren(t *testing.T) {
+	// Build a prototype whose children slice has spare capacity.
+	base := Ul().AddChild(Li().Text("one")).AddChild(Li().Text("two"))
+	a := base.AddChild(Li().Text("a"))
+	b := base.AddChild(Li().Text("b"))
+
+	if got, want := base.Render(), `<ul><li>one</li><li>two</li></ul>`; got != want {
+		t.Errorf("base changed: got %s, want %s", got, want)
+	}
+	if got, want := a.Render(), `<ul><li>one</li><li>two</li><li>a</li></ul>`; got != want {
+		t.Errorf("a: got %s, want %s", got, want)
+	}
+	if got, want := b.Render(), `<ul><li>one</li><li>two</li><li>b</li></ul>`; got != want {
+		t.Errorf("b: got %s, want %s", got, want)
+	}
+}
+
+func TestDerivedElementsConcurrently(t *testing.T) {
+	// Each goroutine appends to the same prototype; only one of them may use its spare
+	// capacity, and the others must copy.
+	base := Ul().AddChild(Li().Text("one")).IDAttr("list")
+	var wg sync.WaitGroup
+	got := make([]string, 8)
+	for i := range got {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ident := strconv.Itoa(i)
+			got[i] = base.ClassAttr("c" + ident).AddChild(Li().Text(ident)).Render()
+		}()
+	}
+	wg.Wait()
+	for i, g := range got {
+		ident := strconv.Itoa(i)
+		if want := `<ul id="list" class="c` + ident + `"><li>one</li><li>` + ident + `</li></ul>`; g != want {
+			t.Errorf("goroutine %d: got %s, want %s", i, g, want)
+		}
+	}
+}
+
+func TestBuildingAllocatesGeometrically(t *testing.T) {
+	var br Renderable = Br()
+	allocs := testing.AllocsPerRun(100, func() {
+		e := Div()
+		for range 64 {
+			e = e.AddChild(br)
+		}
+	})
+	if allocs > 10 {
+		t.Errorf("adding 64 children took %v allocations", allocs)
+	}
+}
+
+func BenchmarkBuildFlightCard(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = FlightCard().IdentAttr("UA123").FlightNumberAttr("123").AirlineNameAttr("United").
+			OriginIataAttr("SFO").DestIataAttr("EWR").GateAttr("B12").StatusTextAttr("On time").ClassAttr("live")
+	}
+}
+
+func TestBooleanAttributes(t *testing.T) {
+	tests := []struct {
+		name      string
+		component Element
+		want      string
+	}{
+		{"bare", Button().DisabledAttr(true).Text("Go"), `<button disabled>Go</button>`},
+		{"off", Button().DisabledAttr(false).Text("Go"), `<button>Go</button>`},
+		{"removed", Button().DisabledAttr(true).DisabledAttr(false).Text("Go"), `<button>Go</button>`},
+		{"mixed", Input().TypeAttr("checkbox").CheckedAttr(true).NameAttr("x").RequiredAttr(true), `<input type="checkbox" checked name="x" required>`},
+		{"custom", MdFilledButton().BoolAttr("has-icon", true).BoolAttr("soft-disabled", false), `<md-filled-button has-icon></md-filled-button>`},
+		{"replaces value", Div().Attr("hidden", "until-found").HiddenAttr(true), `<div hidden></div>`},
+		{"value replaces bool", Div().HiddenAttr(true).Attr("hidden", "until-found"), `<div hidden="until-found"></div>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.component.Render(); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBoolAttrDoesNotModifyPrototype(t *testing.T) {
+	base := Button().DisabledAttr(true).ClassAttr("primary")
+	enabled := base.DisabledAttr(false)
+	if got, want := base.Render(), `<button disabled class="primary"></button>`; got != want {
+		t.Errorf("base changed: got %s, want %s", got, want)
+	}
+	if got, want := enabled.Render(), `<button class="primary"></button>`; got != want {
+		t.Errorf("enabled: got %s, want %s", got, want)
+	}
+}
+
+func TestIndentedRendering(t *testing.T) {
+	component := HTML().LangAttr("en-US").AddChild(
+		Head().AddChild(
+			Title("Departures"),
+			Style().Text("main > section {\n  display: grid;\n}"),
+		),
+		Body().AddChild(
+			Main().AddChild(
+				Section().ClassAttr("results").AddChild(
+					H2().Text("Results"),
+					ResultsCard().AddChild(
+						FlightCard().IdentAttr("UA123"),
+						FlightCard().IdentAttr("UA456"),
+					),
+				),
+				P().AddChild(
+					Content("Need help? "),
+					A().HrefAttr("/help").Text("Contact us"),
+					Content("."),
+				),
+				Div().AddChild(Span().Text("a"), Span().Text("b")),
+				P().AddChild(MdIconButton().Text("edit"), MdIconButton().Text("delete")),
+				Pre().AddChild(Code().Text("line 1\n  line 2")),
+				Form().AddChild(
+					Textarea().NameAttr("notes").Text("keep\n  as is"),
+					Input().TypeAttr("submit"),
+				),
+			),
+			Script().Text("console.log('ready');"),
+		),
+	)
+
+	var renderedHTML strings.Builder
+	if err := component.RenderStreamWith(&renderedHTML, Options{Indent: "  "}); err != nil {
+		t.Fatalf("failed to render component: %v", err)
+	}
+	checkGolden(t, "TestIndentedRendering", renderedHTML.String())
+}
+
+func TestEmptyOptionsMatchRenderStream(t *testing.T) {
+	component := Div().ClassAttr("grid").AddChild(Div().Text("a"), Div().Text("b"))
+	if got, want := component.RenderWith(Options{}), component.Render(); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestInspection(t *testing.T) {
+	card := FlightCard().IDAttr("UA123").ClassAttr("live delayed").HiddenAttr(true).AddChild(
+		Span().Text("Boarding"),
+		Content(" now"),
+	)
+
+	if got := card.Tag(); got != "flight-card" {
+		t.Errorf("Tag() = %q", got)
+	}
+	if v, ok := card.GetAttr("id"); !ok || v != "UA123" {
+		t.Errorf(`GetAttr("id") = %q, %v`, v, ok)
+	}
+	if v, ok := card.GetAttr("hidden"); !ok || v != "" {
+		t.Errorf(`GetAttr("hidden") = %q, %v`, v, ok)
+	}
+	if _, ok := card.GetAttr("gate"); ok {
+		t.Error(`GetAttr("gate") reported a missing attribute as set`)
+	}
+	if !card.HasClass("delayed") || card.HasClass("late") {
+		t.Error("HasClass reported the wrong classes")
+	}
+
+	var keys []string
+	for k := range card.Attrs() {
+		keys = append(keys, k)
+	}
+	if got, want := strings.Join(keys, ","), "id,class,hidden"; got != want {
+		t.Errorf("Attrs() keys = %s, want %s", got, want)
+	}
+
+	children := card.Children()
+	if len(children) != 2 {
+		t.Fatalf("Children() returned %d nodes", len(children))
+	}
+	if span, ok := children[0].(Element); !ok || span.TextValue() != "Boarding" {
+		t.Errorf("first child = %#v", children[0])
+	}
+	children[0] = Content("replaced")
+	if card.Children()[0].Render() != "<span>Boarding</span>" {
+		t.Error("modifying the Children() result changed the element")
+	}
+
+	if card.IsVoid() || !Img().IsVoid() {
+		t.Error("IsVoid reported the wrong value")
+	}
+}
+
+func TestRemoveAttrAndClass(t *testing.T) {
+	base := Button().IDAttr("go").ClassAttr("primary wide").DisabledAttr(true)
+
+	tests := []struct {
+		name      string
+		component Element
+		want      string
+	}{
+		{"remove attr", base.RemoveAttr("id"), `<button class="primary wide" disabled></button>`},
+		{"remove missing attr", base.RemoveAttr("title"), `<button id="go" class="primary wide" disabled></button>`},
+		{"remove class", base.RemoveClass("wide"), `<button id="go" class="primary" disabled></button>`},
+		{"remove last classes", base.RemoveClass("primary", "wide"), `<button id="go" disabled></button>`},
+		{"remove missing class", base.RemoveClass("huge"), `<button id="go" class="primary wide" disabled></button>`},
+		{"prototype untouched", base, `<button id="go" class="primary wide" disabled></button>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.component.Render(); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func detailRow(term, def string) Fragment {
+	return Fragment{Dt().Text(term), Dd().Text(def)}
+}
+
+func TestFragmentRendering(t *testing.T) {
+	component := Dl().AddChild(
+		detailRow("From", "SFO"),
+		detailRow("To", "EWR"),
+		Fragment{Fragment{Dt().Text("Gate")}, Dd().Text("B12")},
+	)
+	want := `<dl><dt>From</dt><dd>SFO</dd><dt>To</dt><dd>EWR</dd><dt>Gate</dt><dd>B12</dd></dl>`
+	if got := component.Render(); got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+	if n := len(component.Children()); n != 6 {
+		t.Errorf("AddChild kept %d children, want the 6 flattened nodes", n)
+	}
+	if got, want := detailRow("From", "SFO").Render(), `<dt>From</dt><dd>SFO</dd>`; got != want {
+		t.Errorf("Fragment.Render() = %s, want %s", got, want)
+	}
+}
+
+func TestFragmentIndentedRendering(t *testing.T) {
+	rows := Fragment{Tr().AddChild(Td().Text("UA1")), Tr().AddChild(Td().Text("UA2"))}
+	var b strings.Builder
+	if err := rows.renderNode(newRenderer(context.Background(), &b, Options{Indent: "  "})); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := b.String(), "<tr>\n  <td>UA1</td>\n</tr>\n<tr>\n  <td>UA2</td>\n</tr>"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	got := Tbody().AddChild(rows).RenderWith(Options{Indent: "  "})
+	if want := "<tbody>\n  <tr>\n    <td>UA1</td>\n  </tr>\n  <tr>\n    <td>UA2</td>\n  </tr>\n</tbody>"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestFragmentTraversal(t *testing.T) {
+	rows := Fragment{FlightCard().IDAttr("UA1"), Fragment{FlightCard().IDAttr("UA2")}}
+	if got := ids(Query(rows, "flight-card:nth-child(2)")); got != "UA2" {
+		t.Errorf("Query through fragment = %q", got)
+	}
+	if _, ok := FindByID(rows, "UA2"); !ok {
+		t.Error("FindByID did not look inside nested fragments")
+	}
+	out := Transform(rows, func(e Element) Element { return e.ClassAttr("live") })
+	if got, want := out.Render(), `<flight-card id="UA1" class="live"></flight-card><flight-card id="UA2" class="live"></flight-card>`; got != want {
+		t.Errorf("Transform = %s, want %s", got, want)
+	}
+}
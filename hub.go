@@ -0,0 +1,323 @@
+package htma
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// DefaultQueueSize is the number of updates a Hub queues for each client by default.
+const DefaultQueueSize = 16
+
+// DropPolicy decides what a Hub does when a client's queue is full.
+type DropPolicy int
+
+const (
+	// DropOldest discards the oldest queued update to make room for the new one.
+	DropOldest DropPolicy = iota
+	// DropNewest discards the new update.
+	DropNewest
+	// Disconnect ends the slow client's stream.
+	Disconnect
+)
+
+// ErrSlowClient is returned by Hub.Stream when the client is disconnected by the
+// Disconnect policy for falling behind.
+var ErrSlowClient = errors.New("htma: client too slow for live updates")
+
+// hubOptions holds the settings of a Hub.
+type hubOptions struct {
+	queueSize int
+	policy    DropPolicy
+}
+
+// HubOption configures a Hub.
+type HubOption func(*hubOptions)
+
+// WithQueueSize sets the number of updates queued for each client. It panics if n
+// is less than 1, since a client without a queue could never be sent an update.
+func WithQueueSize(n int) HubOption {
+	if n < 1 {
+		panic(fmt.Sprintf("htma: WithQueueSize(%d): queue size must be at least 1", n))
+	}
+	return func(o *hubOptions) { o.queueSize = n }
+}
+
+// WithDropPolicy sets what happens when a client's queue is full. The default is DropOldest.
+func WithDropPolicy(p DropPolicy) HubOption {
+	return func(o *hubOptions) { o.policy = p }
+}
+
+// Hub pushes changes to live components to every client streaming them.
+// Components are registered by key with Live or Update, and each Update sends the
+// patches from Diff to the clients subscribed to its key, until it is dropped with
+// Remove. A client whose updates were dropped is sent the whole component again, or
+// its removal, before it receives more patches.
+//
+// Live components are matched by id when sent whole, so the root of each should be
+// an element with an id. A Hub is safe for concurrent use.
+type Hub struct {
+	mu      sync.Mutex
+	opts    hubOptions
+	version uint64 // last version given to a component, so versions are never reused
+	live    map[string]liveComponent
+	subs    map[string]map[*subscriber]struct{}
+}
+
+// liveComponent is the current version of a component.
+type liveComponent struct {
+	r       Renderable
+	version uint64
+}
+
+// hubUpdate holds the patches taking a component to a new version, or removing it.
+type hubUpdate struct {
+	key     string
+	version uint64
+	patches []ElementPatch
+	removed bool
+}
+
+// subscriber is a client streaming live components. Its stale and gone sets are
+// guarded by the hub's mutex; synced is only used by the goroutine running Stream.
+type subscriber struct {
+	keys    []string
+	updates chan hubUpdate
+	stale   map[string]bool         // keys with dropped updates, to be sent whole
+	gone    map[string]ElementPatch // removals among the dropped updates, by key
+	synced  map[string]uint64       // version of each key the client has
+	kicked  chan struct{}           // closed when the client is disconnected for being slow
+}
+
+// NewHub returns an empty Hub.
+func NewHub(opts ...HubOption) *Hub {
+	h := &Hub{
+		opts: hubOptions{queueSize: DefaultQueueSize, policy: DropOldest},
+		live: make(map[string]liveComponent),
+		subs: make(map[string]map[*subscriber]struct{}),
+	}
+	for _, opt := range opts {
+		opt(&h.opts)
+	}
+	return h
+}
+
+// Live records r as the current version of the component with the given key and
+// returns it, so it can be used in place while building a page. If r differs from
+// the version clients have, it is sent to them as by Update.
+func (h *Hub) Live(key string, r Renderable) Renderable {
+	h.Update(key, r)
+	return r
+}
+
+// Update makes r the current version of the component with the given key and queues
+// the patches from the previous version for every client subscribed to it. It never
+// blocks on slow clients; their queues are handled by the hub's DropPolicy.
+//
+// The patches are computed without holding the hub's lock. If the component changes
+// in the meantime, they are computed again against the newer version.
+func (h *Hub) Update(key string, r Renderable) {
+	for {
+		h.mu.Lock()
+		prev, ok := h.live[key]
+		h.mu.Unlock()
+
+		patches := []ElementPatch{{Mode: ModeOuter, Elements: r}}
+		if ok {
+			patches = Diff(prev.r, r)
+		}
+
+		h.mu.Lock()
+		if cur, still := h.live[key]; still != ok || cur.version != prev.version {
+			h.mu.Unlock()
+			continue // updated or removed while diffing
+		}
+		if len(patches) > 0 {
+			h.version++
+			h.live[key] = liveComponent{r: r, version: h.version}
+			h.publish(hubUpdate{key: key, version: h.version, patches: patches})
+		}
+		h.mu.Unlock()
+		return
+	}
+}
+
+// Remove drops the component with the given key and sends clients subscribed to it
+// a patch removing it from the page. The component is found by the id of its root
+// element, or by its tag name if it has none, as with Diff; a component whose root
+// is not an Element is only forgotten. Removing a key that is not live does nothing.
+func (h *Hub) Remove(key string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	c, ok := h.live[key]
+	if !ok {
+		return
+	}
+	delete(h.live, key)
+	h.version++
+	e, ok := c.r.(Element)
+	if !ok {
+		return
+	}
+	sel := e.tag
+	if id, ok := e.getAttr("id"); ok {
+		sel = idSelector(id)
+	}
+	h.publish(hubUpdate{
+		key:     key,
+		version: h.version,
+		patches: []ElementPatch{{Mode: ModeRemove, Selector: sel}},
+		removed: true,
+	})
+}
+
+// publish queues u for every client subscribed to its key. The caller must hold h.mu.
+func (h *Hub) publish(u hubUpdate) {
+	for s := range h.subs[u.key] {
+		h.enqueue(s, u)
+	}
+}
+
+// enqueue queues u for s, applying the drop policy if its queue is full.
+func (h *Hub) enqueue(s *subscriber, u hubUpdate) {
+	select {
+	case s.updates <- u:
+		return
+	default:
+	}
+	switch h.opts.policy {
+	case DropNewest:
+		s.drop(u)
+	case DropOldest:
+		select {
+		case dropped := <-s.updates:
+			s.drop(dropped)
+		default:
+		}
+		select {
+		case s.updates <- u:
+		default:
+			s.drop(u)
+		}
+	case Disconnect:
+		h.remove(s)
+		close(s.kicked)
+	}
+}
+
+// drop records that s missed u, so that resync sends the component whole or, if u
+// removed it, sends the removal. Updates are dropped in order, so the last one decides.
+// The caller must hold h.mu.
+func (s *subscriber) drop(u hubUpdate) {
+	s.stale[u.key] = true
+	if u.removed {
+		s.gone[u.key] = u.patches[0]
+	} else {
+		delete(s.gone, u.key)
+	}
+}
+
+// Stream sends the live components with the given keys to the client as Datastar
+// events until the request's context is done. Each component is first sent whole,
+// so updates made between rendering the page and connecting are not lost.
+// Stream returns nil when the client goes away, ErrSlowClient if it is disconnected
+// by the Disconnect policy, or the error from writing an event.
+func (h *Hub) Stream(w http.ResponseWriter, r *http.Request, keys ...string) error {
+	sse := NewSSE(w, r)
+	s := h.subscribe(keys)
+	defer h.unsubscribe(s)
+
+	if err := h.resync(sse, s); err != nil {
+		return streamError(sse, err)
+	}
+	for {
+		select {
+		case <-sse.Context().Done():
+			return nil
+		case <-s.kicked:
+			return ErrSlowClient
+		case u := <-s.updates:
+			if err := h.resync(sse, s); err != nil {
+				return streamError(sse, err)
+			}
+			if u.version <= s.synced[u.key] {
+				continue // the client already has this version
+			}
+			if err := sse.SendPatches(u.patches); err != nil {
+				return streamError(sse, err)
+			}
+			s.synced[u.key] = u.version
+		}
+	}
+}
+
+// streamError returns nil for errors caused by the client going away.
+func streamError(sse *SSE, err error) error {
+	if sse.Context().Err() != nil {
+		return nil
+	}
+	return err
+}
+
+func (h *Hub) subscribe(keys []string) *subscriber {
+	s := &subscriber{
+		keys:    keys,
+		updates: make(chan hubUpdate, h.opts.queueSize),
+		stale:   make(map[string]bool),
+		gone:    make(map[string]ElementPatch),
+		synced:  make(map[string]uint64),
+		kicked:  make(chan struct{}),
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, key := range keys {
+		if h.subs[key] == nil {
+			h.subs[key] = make(map[*subscriber]struct{})
+		}
+		h.subs[key][s] = struct{}{}
+		s.stale[key] = true
+	}
+	return s
+}
+
+func (h *Hub) unsubscribe(s *subscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.remove(s)
+}
+
+// remove drops s from the subscriptions. The caller must hold h.mu.
+func (h *Hub) remove(s *subscriber) {
+	for _, key := range s.keys {
+		delete(h.subs[key], s)
+		if len(h.subs[key]) == 0 {
+			delete(h.subs, key)
+		}
+	}
+}
+
+// resync sends the whole current version of every stale component of s, and the
+// removal of stale components that are no longer live.
+func (h *Hub) resync(sse *SSE, s *subscriber) error {
+	h.mu.Lock()
+	var send []ElementPatch
+	for _, key := range s.keys {
+		if !s.stale[key] {
+			continue
+		}
+		if c, ok := h.live[key]; ok {
+			send = append(send, ElementPatch{Mode: ModeOuter, Elements: c.r})
+			s.synced[key] = c.version
+		} else if p, ok := s.gone[key]; ok {
+			send = append(send, p)
+			s.synced[key] = h.version
+		}
+		delete(s.stale, key)
+		delete(s.gone, key)
+	}
+	h.mu.Unlock()
+
+	return sse.SendPatches(send)
+}
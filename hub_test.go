@@ -0,0 +1,279 @@
+package htma
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readEvent reads one server-sent event and returns its lines without the blank line.
+func readEvent(t *testing.T, r *bufio.Reader) string {
+	t.Helper()
+	var lines []string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("reading event: %v", err)
+		}
+		if line == "\n" {
+			return strings.Join(lines, "")
+		}
+		lines = append(lines, line)
+	}
+}
+
+// waitForSubscribers waits until the hub has n subscribers for key.
+func waitForSubscribers(t *testing.T, h *Hub, key string, n int) {
+	t.Helper()
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		h.mu.Lock()
+		got := len(h.subs[key])
+		h.mu.Unlock()
+		if got == n {
+			return
+		}
+	}
+	t.Fatalf("timed out waiting for %d subscribers of %q", n, key)
+}
+
+func TestHubStream(t *testing.T) {
+	hub := NewHub()
+	hub.Live("UA1", departureRow("UA1", "On time"))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hub.Stream(w, r, "UA1", "AA3")
+	}))
+	defer srv.Close()
+
+	var clients []*bufio.Reader
+	var cancels []context.CancelFunc
+	for range 2 {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancels = append(cancels, cancel)
+		req, _ := http.NewRequestWithContext(ctx, "GET", srv.URL, nil)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body := bufio.NewReader(resp.Body)
+		want := "event: datastar-patch-elements\n" +
+			`data: elements <li id="UA1"><span class="ident">UA1</span><span class="status">On time</span></li>` + "\n"
+		if got := readEvent(t, body); got != want {
+			t.Fatalf("initial event:\n%s\nwant\n%s", got, want)
+		}
+		clients = append(clients, body)
+	}
+
+	hub.Update("UA1", departureRow("UA1", "Delayed"))
+	hub.Update("AA3", departureRow("AA3", "Boarding"))
+	hub.Update("DL2", departureRow("DL2", "Boarding")) // no subscribers
+	for i, body := range clients {
+		want := "event: datastar-patch-elements\n" +
+			"data: selector #UA1 > :nth-child(2)\n" +
+			"data: mode inner\n" +
+			"data: elements Delayed\n"
+		if got := readEvent(t, body); got != want {
+			t.Errorf("client %d update:\n%s\nwant\n%s", i, got, want)
+		}
+		want = "event: datastar-patch-elements\n" +
+			`data: elements <li id="AA3"><span class="ident">AA3</span><span class="status">Boarding</span></li>` + "\n"
+		if got := readEvent(t, body); got != want {
+			t.Errorf("client %d new component:\n%s\nwant\n%s", i, got, want)
+		}
+	}
+
+	cancels[0]()
+	waitForSubscribers(t, hub, "UA1", 1)
+	cancels[1]()
+	waitForSubscribers(t, hub, "UA1", 0)
+	waitForSubscribers(t, hub, "AA3", 0)
+}
+
+func TestHubDropPolicies(t *testing.T) {
+	tests := []struct {
+		policy  DropPolicy
+		queued  string // text of the queued update
+		stale   bool
+		removed bool
+	}{
+		{DropOldest, "3", true, false},
+		{DropNewest, "1", true, false},
+		{Disconnect, "1", false, true},
+	}
+	for _, tt := range tests {
+		hub := NewHub(WithQueueSize(1), WithDropPolicy(tt.policy))
+		s := hub.subscribe([]string{"k"})
+		s.stale = make(map[string]bool)
+		for _, text := range []string{"1", "2", "3"} {
+			hub.Update("k", Div().IDAttr("k").Text(text))
+		}
+
+		u := <-s.updates
+		if got := u.patches[0].Elements.Render(); !strings.Contains(got, tt.queued) {
+			t.Errorf("policy %d: queued %s, want version %s", tt.policy, got, tt.queued)
+		}
+		if s.stale["k"] != tt.stale {
+			t.Errorf("policy %d: stale = %v, want %v", tt.policy, s.stale["k"], tt.stale)
+		}
+		select {
+		case <-s.kicked:
+			if !tt.removed {
+				t.Errorf("policy %d: subscriber disconnected", tt.policy)
+			}
+		default:
+			if tt.removed {
+				t.Errorf("policy %d: subscriber not disconnected", tt.policy)
+			}
+		}
+		if _, subscribed := hub.subs["k"][s]; subscribed == tt.removed {
+			t.Errorf("policy %d: subscribed = %v", tt.policy, subscribed)
+		}
+	}
+}
+
+func TestWithQueueSizePanics(t *testing.T) {
+	for _, n := range []int{0, -1} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("WithQueueSize(%d) did not panic", n)
+				}
+			}()
+			WithQueueSize(n)
+		}()
+	}
+}
+
+// pipeResponse is a ResponseWriter whose writes block until the test reads them.
+type pipeResponse struct {
+	header http.Header
+	w      *io.PipeWriter
+}
+
+func (p *pipeResponse) Header() http.Header         { return p.header }
+func (p *pipeResponse) Write(b []byte) (int, error) { return p.w.Write(b) }
+func (p *pipeResponse) WriteHeader(int)             {}
+func (p *pipeResponse) Flush()                      {}
+
+func TestHubResyncsAfterDrops(t *testing.T) {
+	hub := NewHub(WithQueueSize(1), WithDropPolicy(DropNewest))
+	hub.Live("k", Div().IDAttr("k").Text("1"))
+
+	pr, pw := io.Pipe()
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest("GET", "/live", nil).WithContext(ctx)
+	done := make(chan error)
+	go func() {
+		done <- hub.Stream(&pipeResponse{header: make(http.Header), w: pw}, req, "k")
+	}()
+	body := bufio.NewReader(pr)
+	readEvent(t, body)
+
+	// The client is not reading, so the stream blocks and updates are dropped.
+	for _, text := range []string{"2", "3", "4", "5"} {
+		hub.Update("k", Div().IDAttr("k").Text(text))
+	}
+	var last string
+	for !strings.Contains(last, ">5<") {
+		last = readEvent(t, body)
+	}
+	if want := "event: datastar-patch-elements\ndata: elements <div id=\"k\">5</div>\n"; last != want {
+		t.Errorf("after drops got\n%s\nwant the whole component\n%s", last, want)
+	}
+
+	cancel()
+	pr.Close()
+	if err := <-done; err != nil {
+		t.Errorf("Stream: %v", err)
+	}
+	waitForSubscribers(t, hub, "k", 0)
+}
+
+func TestHubRemove(t *testing.T) {
+	hub := NewHub()
+	hub.Live("UA1", departureRow("UA1", "On time"))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hub.Stream(w, r, "UA1")
+	}))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body := bufio.NewReader(resp.Body)
+	readEvent(t, body)
+
+	hub.Remove("UA1")
+	hub.Remove("UA1") // already removed
+	want := "event: datastar-patch-elements\n" +
+		"data: selector #UA1\n" +
+		"data: mode remove\n"
+	if got := readEvent(t, body); got != want {
+		t.Errorf("removal:\n%s\nwant\n%s", got, want)
+	}
+	hub.mu.Lock()
+	n := len(hub.live)
+	hub.mu.Unlock()
+	if n != 0 {
+		t.Errorf("%d live components after Remove", n)
+	}
+
+	hub.Update("UA1", departureRow("UA1", "Boarding"))
+	want = "event: datastar-patch-elements\n" +
+		`data: elements <li id="UA1"><span class="ident">UA1</span><span class="status">Boarding</span></li>` + "\n"
+	if got := readEvent(t, body); got != want {
+		t.Errorf("after removal:\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestHubResyncsDroppedRemoval(t *testing.T) {
+	hub := NewHub(WithQueueSize(1), WithDropPolicy(DropNewest))
+	hub.Live("k", Div().IDAttr("k").Text("1"))
+	s := hub.subscribe([]string{"k"})
+	delete(s.stale, "k")
+
+	hub.Update("k", Div().IDAttr("k").Text("2"))
+	hub.Remove("k") // dropped: the queue is full
+	if p, ok := s.gone["k"]; !s.stale["k"] || !ok || p.Mode != ModeRemove {
+		t.Fatalf("stale = %v, gone = %+v", s.stale["k"], s.gone)
+	}
+
+	rec := httptest.NewRecorder()
+	if err := hub.resync(NewSSE(rec, httptest.NewRequest("GET", "/live", nil)), s); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := rec.Body.String(), "event: datastar-patch-elements\ndata: selector #k\ndata: mode remove\n\n"; got != want {
+		t.Errorf("resync sent\n%s\nwant\n%s", got, want)
+	}
+	if u := <-s.updates; u.version > s.synced["k"] {
+		t.Errorf("queued update %d would be sent after the removal (synced %d)", u.version, s.synced["k"])
+	}
+}
+
+func TestHubDiffsWithoutLock(t *testing.T) {
+	hub := NewHub()
+	var locked bool
+	row := func(status string) Element {
+		return Div().IDAttr("k").AddChild(Func(func(w io.Writer) error {
+			if hub.mu.TryLock() {
+				hub.mu.Unlock()
+			} else {
+				locked = true
+			}
+			_, err := io.WriteString(w, status)
+			return err
+		}))
+	}
+	hub.Update("k", row("On time"))
+	hub.Update("k", row("Delayed"))
+	if locked {
+		t.Error("Update held the hub's lock while diffing")
+	}
+}
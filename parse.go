@@ -0,0 +1,561 @@
+package htma
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+)
+
+// ParseError reports malformed HTML and where it was found.
+type ParseError struct {
+	Line   int // 1-based line number
+	Column int // 1-based column, in bytes
+	Msg    string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("htma: parse error at line %d, column %d: %s", e.Line, e.Column, e.Msg)
+}
+
+// Parse reads an HTML document or fragment and returns it as a tree of Element,
+// TextContent and Raw nodes. A leading doctype is accepted and dropped, since the
+// html element renders its own. Comments are kept as Raw nodes.
+//
+// Parse is strict about nesting: apart from the end tags HTML allows to be omitted
+// (p, li, td, option and the like), every element must be closed, and closing tags
+// must match. Malformed input yields a *ParseError with the line and column.
+func Parse(r io.Reader) ([]Renderable, error) {
+	return parse(r, true)
+}
+
+// ParseFragment is like Parse but for markup that is not a full document;
+// a doctype is reported as an error.
+func ParseFragment(r io.Reader) ([]Renderable, error) {
+	return parse(r, false)
+}
+
+// ParseFragmentLenient is like ParseFragment but accepts the messy markup people
+// write by hand. End tags with no open element are dropped, an end tag closes any
+// elements left open inside it, elements still open at the end of the input are
+// closed there, and anything that cannot be parsed as markup, such as an
+// unterminated tag or comment, is kept as text. It only fails if r does.
+func ParseFragmentLenient(r io.Reader) ([]Renderable, error) {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{src: string(src), lenient: true}
+	return p.run()
+}
+
+func parse(r io.Reader, document bool) ([]Renderable, error) {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{src: string(src), document: document}
+	return p.run()
+}
+
+// openElement is an element whose end tag has not been seen yet.
+type openElement struct {
+	el       Element
+	children []Renderable
+	pos      int
+}
+
+type parser struct {
+	src      string
+	pos      int
+	document bool
+	lenient  bool // see ParseFragmentLenient
+	stack    []*openElement
+	roots    []Renderable
+	open     map[string]int // number of open elements by tag
+	misses   map[string]int // earliest offset from which each searched string is known to be absent
+
+	failedAttrs map[attrScan]bool // see parseAttrs
+}
+
+// attrScan is a point at which the parser started reading an attribute.
+type attrScan struct {
+	pos     int
+	foreign bool // whether names keep their case
+}
+
+// optionalEndTags lists elements whose end tag may be omitted.
+var optionalEndTags = map[string]bool{
+	"html": true, "head": true, "body": true, "p": true, "li": true, "dt": true,
+	"dd": true, "option": true, "optgroup": true, "colgroup": true, "caption": true,
+	"thead": true, "tbody": true, "tfoot": true, "tr": true, "td": true, "th": true,
+	"rb": true, "rt": true, "rp": true,
+}
+
+// closesParagraph lists start tags that implicitly end an open p element.
+var closesParagraph = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "details": true,
+	"dialog": true, "div": true, "dl": true, "fieldset": true, "figcaption": true,
+	"figure": true, "footer": true, "form": true, "h1": true, "h2": true, "h3": true,
+	"h4": true, "h5": true, "h6": true, "header": true, "hgroup": true, "hr": true,
+	"main": true, "menu": true, "nav": true, "ol": true, "p": true, "pre": true,
+	"search": true, "section": true, "table": true, "ul": true,
+}
+
+// impliedEndTags maps a start tag to the open elements it implicitly closes.
+var impliedEndTags = map[string][]string{
+	"li":       {"li"},
+	"dt":       {"dt", "dd"},
+	"dd":       {"dt", "dd"},
+	"option":   {"option"},
+	"optgroup": {"option", "optgroup"},
+	"tr":       {"td", "th", "tr"},
+	"td":       {"td", "th"},
+	"th":       {"td", "th"},
+	"thead":    {"td", "th", "tr", "thead", "tbody", "tfoot", "caption", "colgroup"},
+	"tbody":    {"td", "th", "tr", "thead", "tbody", "tfoot", "caption", "colgroup"},
+	"tfoot":    {"td", "th", "tr", "thead", "tbody", "tfoot", "caption", "colgroup"},
+	"body":     {"head"},
+	"rt":       {"rb", "rt", "rp"},
+	"rp":       {"rb", "rt", "rp"},
+}
+
+// rcdataTags lists elements whose content is text with character references but no tags.
+var rcdataTags = map[string]bool{
+	"textarea": true,
+	"title":    true,
+}
+
+func (p *parser) run() ([]Renderable, error) {
+	for p.pos < len(p.src) {
+		var err error
+		start := p.pos
+		switch {
+		case strings.HasPrefix(p.src[p.pos:], "<!--"):
+			err = p.parseComment()
+		case strings.HasPrefix(p.src[p.pos:], "<!"):
+			err = p.parseDeclaration()
+		case strings.HasPrefix(p.src[p.pos:], "</") && p.pos+2 < len(p.src) && isASCIILetter(p.src[p.pos+2]):
+			err = p.parseEndTag()
+		case p.src[p.pos] == '<' && p.pos+1 < len(p.src) && isASCIILetter(p.src[p.pos+1]):
+			err = p.parseStartTag()
+		default:
+			p.parseText()
+		}
+		if err != nil && p.lenient {
+			// Keep the "<" that failed to start markup as text and carry on after it.
+			p.pos = start + 1
+			p.appendNode(Content("<"))
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	for len(p.stack) > 0 {
+		top := p.stack[len(p.stack)-1]
+		if !optionalEndTags[top.el.tag] && !p.lenient {
+			return nil, p.errorAt(top.pos, "unclosed <%s>", top.el.tag)
+		}
+		p.pop()
+	}
+	return p.roots, nil
+}
+
+// errSkip is returned instead of a ParseError in lenient mode, where errors only
+// make the parser keep the markup as text and their position is not needed.
+var errSkip = errors.New("htma: skip malformed markup")
+
+func (p *parser) errorAt(pos int, format string, args ...any) error {
+	if p.lenient {
+		return errSkip
+	}
+	line := 1 + strings.Count(p.src[:pos], "\n")
+	col := pos - strings.LastIndex(p.src[:pos], "\n")
+	return &ParseError{Line: line, Column: col, Msg: fmt.Sprintf(format, args...)}
+}
+
+// index returns the offset of the first match of needle at or after from, or -1.
+func (p *parser) index(from int, needle string) int {
+	return p.search(from, needle, len(needle), func(s string) int { return strings.Index(s, needle) })
+}
+
+// search returns the offset of the first match of find in the input at or after from,
+// or -1. Matches are width bytes long. Failed searches are remembered by key, so that
+// lenient parsing of input with many unterminated constructs does not search the rest
+// of the input again for each of them.
+func (p *parser) search(from int, key string, width int, find func(string) int) int {
+	end := len(p.src)
+	if miss, ok := p.misses[key]; ok {
+		if from >= miss {
+			return -1
+		}
+		end = min(end, miss+width-1)
+	}
+	if i := find(p.src[from:end]); i >= 0 {
+		return from + i
+	}
+	if p.misses == nil {
+		p.misses = make(map[string]int)
+	}
+	p.misses[key] = from
+	return -1
+}
+
+// appendNode adds n to the innermost open element, or to the roots.
+func (p *parser) appendNode(n Renderable) {
+	if len(p.stack) == 0 {
+		if t, ok := n.(TextContent); ok && strings.TrimSpace(t.Content) == "" {
+			return
+		}
+		p.roots = append(p.roots, n)
+		return
+	}
+	top := p.stack[len(p.stack)-1]
+	top.children = append(top.children, n)
+}
+
+// pop closes the innermost open element and attaches it to its parent.
+func (p *parser) pop() {
+	top := p.stack[len(p.stack)-1]
+	p.stack = p.stack[:len(p.stack)-1]
+	p.open[top.el.tag]--
+	el := top.el
+	if t, ok := singleText(top.children); ok {
+		el.text = t
+	} else {
+		el.children = top.children
+	}
+	p.appendNode(el)
+}
+
+// singleText reports whether children is exactly one text node, which is stored as element text.
+func singleText(children []Renderable) (string, bool) {
+	if len(children) != 1 {
+		return "", false
+	}
+	t, ok := children[0].(TextContent)
+	return t.Content, ok
+}
+
+// inForeignContent reports whether the parser is inside svg or math, where names keep their case.
+func (p *parser) inForeignContent() bool {
+	return p.open["svg"] > 0 || p.open["math"] > 0
+}
+
+func (p *parser) parseText() {
+	start := p.pos
+	p.pos++
+	for p.pos < len(p.src) && p.src[p.pos] != '<' {
+		p.pos++
+	}
+	p.appendNode(Content(html.UnescapeString(p.src[start:p.pos])))
+}
+
+func (p *parser) parseComment() error {
+	start := p.pos
+	end := p.index(p.pos+4, "-->")
+	if end < 0 {
+		return p.errorAt(start, "unterminated comment")
+	}
+	p.pos = end + 3
+	p.appendNode(RawContent(p.src[start:p.pos]))
+	return nil
+}
+
+// parseDeclaration handles <!DOCTYPE ...> and CDATA sections.
+func (p *parser) parseDeclaration() error {
+	start := p.pos
+	if strings.HasPrefix(p.src[p.pos:], "<![CDATA[") {
+		end := p.index(p.pos, "]]>")
+		if end < 0 {
+			return p.errorAt(start, "unterminated CDATA section")
+		}
+		p.pos = end + 3
+		p.appendNode(RawContent(p.src[start:p.pos]))
+		return nil
+	}
+	end := p.index(p.pos, ">")
+	if end < 0 {
+		return p.errorAt(start, "unterminated declaration")
+	}
+	end -= p.pos
+	decl := p.src[p.pos : p.pos+end+1]
+	if !strings.HasPrefix(strings.ToLower(decl), "<!doctype") {
+		return p.errorAt(start, "unexpected declaration %q", decl)
+	}
+	if !p.document {
+		return p.errorAt(start, "doctype in fragment")
+	}
+	if len(p.stack) > 0 || len(p.roots) > 0 {
+		return p.errorAt(start, "doctype must come first")
+	}
+	p.pos += end + 1
+	return nil
+}
+
+// readName consumes a tag or attribute name.
+func (p *parser) readName() string {
+	start := p.pos
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		if isSpace(c) || c == '/' || c == '>' || c == '=' || c == '<' || c == '"' || c == '\'' {
+			break
+		}
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+func (p *parser) skipSpace() {
+	for p.pos < len(p.src) && isSpace(p.src[p.pos]) {
+		p.pos++
+	}
+}
+
+func (p *parser) parseStartTag() error {
+	start := p.pos
+	p.pos++
+	tag := strings.ToLower(p.readName())
+	foreign := p.inForeignContent() || tag == "svg" || tag == "math"
+	if err := ValidateTagName(tag); err != nil {
+		return p.errorAt(start, "invalid tag name %q", tag)
+	}
+	el, selfClosing, err := p.parseAttrs(CustomElement(tag), start, foreign)
+	if err != nil {
+		return err
+	}
+
+	p.closeImplied(tag)
+	if el.isVoid || selfClosing {
+		p.appendNode(el)
+		return nil
+	}
+	if rawTextTags[tag] || rcdataTags[tag] {
+		return p.parseTextElement(el, start)
+	}
+	p.stack = append(p.stack, &openElement{el: el, pos: start})
+	if p.open == nil {
+		p.open = make(map[string]int)
+	}
+	p.open[tag]++
+	return nil
+}
+
+// parseAttrs reads the attributes of el up to the end of its start tag, and reports
+// whether the tag was self-closing.
+//
+// Reading attributes from a given offset always ends the same way, so in lenient
+// mode the offsets of attributes that led to an error are remembered, and a later
+// start tag reaching one of them fails at once. Otherwise each "<" inside a long
+// unterminated tag would read the rest of its attributes again.
+func (p *parser) parseAttrs(el Element, start int, foreign bool) (Element, bool, error) {
+	var visited []attrScan
+	fail := func(err error) (Element, bool, error) {
+		if p.lenient {
+			if p.failedAttrs == nil {
+				p.failedAttrs = make(map[attrScan]bool)
+			}
+			for _, v := range visited {
+				p.failedAttrs[v] = true
+			}
+		}
+		return el, false, err
+	}
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.src) {
+			return fail(p.errorAt(start, "unterminated start tag <%s>", el.tag))
+		}
+		if scan := (attrScan{p.pos, foreign}); p.failedAttrs[scan] {
+			return fail(p.errorAt(start, "malformed start tag <%s>", el.tag))
+		} else if p.lenient {
+			visited = append(visited, scan)
+		}
+		c := p.src[p.pos]
+		if c == '>' {
+			p.pos++
+			return el, false, nil
+		}
+		if strings.HasPrefix(p.src[p.pos:], "/>") {
+			p.pos += 2
+			return el, true, nil
+		}
+		if c == '/' {
+			p.pos++
+			continue
+		}
+		attrPos := p.pos
+		name := p.readName()
+		if name == "" {
+			return fail(p.errorAt(attrPos, "unexpected %q in <%s>", c, el.tag))
+		}
+		if !foreign {
+			name = strings.ToLower(name)
+		}
+		if err := ValidateAttrName(name); err != nil {
+			return fail(p.errorAt(attrPos, "invalid attribute name %q in <%s>", name, el.tag))
+		}
+		p.skipSpace()
+		if p.pos >= len(p.src) || p.src[p.pos] != '=' {
+			if _, dup := el.getAttr(name); !dup {
+				el = el.BoolAttr(name, true)
+			}
+			continue
+		}
+		p.pos++
+		p.skipSpace()
+		value, err := p.readAttrValue(attrPos, name)
+		if err != nil {
+			return fail(err)
+		}
+		if _, dup := el.getAttr(name); !dup {
+			el = el.setAttr(name, value)
+		}
+	}
+}
+
+func (p *parser) readAttrValue(attrPos int, name string) (string, error) {
+	if p.pos >= len(p.src) {
+		return "", p.errorAt(attrPos, "missing value for attribute %q", name)
+	}
+	if q := p.src[p.pos]; q == '"' || q == '\'' {
+		end := p.index(p.pos+1, p.src[p.pos:p.pos+1])
+		if end < 0 {
+			return "", p.errorAt(p.pos, "unterminated value for attribute %q", name)
+		}
+		value := p.src[p.pos+1 : end]
+		p.pos = end + 1
+		return html.UnescapeString(value), nil
+	}
+	start := p.pos
+	// An unquoted value ends at whitespace or ">".
+	end := p.search(p.pos, "unquoted value", 1, func(s string) int { return strings.IndexAny(s, " \t\n\r\f>") })
+	if end < 0 {
+		end = len(p.src)
+	}
+	p.pos = end
+	if p.pos == start {
+		return "", p.errorAt(attrPos, "missing value for attribute %q", name)
+	}
+	return html.UnescapeString(p.src[start:p.pos]), nil
+}
+
+// closeImplied pops the elements whose end tags are implied by a new start tag.
+func (p *parser) closeImplied(tag string) {
+	if closesParagraph[tag] && len(p.stack) > 0 && p.stack[len(p.stack)-1].el.tag == "p" {
+		p.pop()
+	}
+	closes := impliedEndTags[tag]
+	for len(p.stack) > 0 {
+		top := p.stack[len(p.stack)-1].el.tag
+		found := false
+		for _, c := range closes {
+			if c == top {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return
+		}
+		p.pop()
+	}
+}
+
+// parseTextElement reads the content of script, style, textarea and title up to
+// the matching end tag, without looking for nested tags.
+func (p *parser) parseTextElement(el Element, start int) error {
+	closing := "</" + el.tag
+	end := p.search(p.pos, closing, len(closing), func(s string) int { return indexASCIIFold(s, closing) })
+	if end < 0 {
+		return p.errorAt(start, "unclosed <%s>", el.tag)
+	}
+	text := p.src[p.pos:end]
+	if rcdataTags[el.tag] {
+		text = html.UnescapeString(text)
+	}
+	p.pos = end + len(closing)
+	gt := p.index(p.pos, ">")
+	if gt < 0 {
+		return p.errorAt(p.pos, "unterminated end tag </%s>", el.tag)
+	}
+	p.pos = gt + 1
+	el.text = text
+	p.appendNode(el)
+	return nil
+}
+
+// indexASCIIFold returns the index of the first match of lower in s, ignoring ASCII
+// case, or -1. lower must be lowercase. Unlike lowercasing s first, this never changes
+// byte offsets, so the index can be used to slice s.
+func indexASCIIFold(s, lower string) int {
+	for i := 0; i+len(lower) <= len(s); i++ {
+		j := 0
+		for j < len(lower) && toLowerASCII(s[i+j]) == lower[j] {
+			j++
+		}
+		if j == len(lower) {
+			return i
+		}
+	}
+	return -1
+}
+
+func toLowerASCII(c byte) byte {
+	if 'A' <= c && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
+
+func (p *parser) parseEndTag() error {
+	start := p.pos
+	p.pos += 2
+	tag := strings.ToLower(p.readName())
+	p.skipSpace()
+	if p.pos >= len(p.src) || p.src[p.pos] != '>' {
+		return p.errorAt(start, "malformed end tag </%s>", tag)
+	}
+	p.pos++
+
+	if ctor, ok := elementConstructors[tag]; ok && ctor.new().isVoid {
+		// End tags of void elements, such as </br>, are ignored.
+		return nil
+	}
+	if p.lenient {
+		if p.open[tag] == 0 {
+			return nil // a stray end tag
+		}
+		for i := len(p.stack) - 1; i >= 0; i-- {
+			if p.stack[i].el.tag == tag {
+				for len(p.stack) > i {
+					p.pop()
+				}
+				return nil
+			}
+		}
+	}
+	for i := len(p.stack) - 1; i >= 0; i-- {
+		open := p.stack[i].el.tag
+		if open == tag {
+			for len(p.stack) > i {
+				p.pop()
+			}
+			return nil
+		}
+		if !optionalEndTags[open] {
+			return p.errorAt(start, "unexpected </%s>, expected </%s>", tag, open)
+		}
+	}
+	return p.errorAt(start, "unexpected </%s> with no open <%s>", tag, tag)
+}
+
+func isASCIILetter(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
@@ -0,0 +1,219 @@
+package htma
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func renderAll(t *testing.T, nodes []Renderable) string {
+	t.Helper()
+	var b strings.Builder
+	for _, n := range nodes {
+		if err := n.RenderStream(&b); err != nil {
+			t.Fatalf("render: %v", err)
+		}
+	}
+	return b.String()
+}
+
+func TestParseRoundTrip(t *testing.T) {
+	tests := []string{
+		`<div class="grid"><div class="a">a</div><div class="b">b</div></div>`,
+		`<main class="main tile"><h1>Team collaboration done right</h1><p>Thousands of teams turn to <b>Ink</b> to get things done.</p></main>`,
+		`<form action="/flights" method="get"><input type="text" name="ident" required><button disabled>Go</button></form>`,
+		`<flight-card ident="UA123" origin-iata="SFO" dest-iata="EWR"></flight-card>`,
+		`<md-filled-button data-on-click="@get(&#39;/flights&#39;)">Search</md-filled-button>`,
+		`<p>a &lt; b &amp;&amp; c</p><br><img src="/logo.png" alt="Logo">`,
+		`<ul><li>one</li><li>two</li></ul><!-- results -->`,
+		`<script>if (a < b && c) { x("</div>"); }</script><style>a > b { color: red; }</style>`,
+		`<textarea name="notes">&lt;b&gt; stays text</textarea>`,
+		`<svg viewBox="0 0 10 10"><path d="M0 0"></path></svg>`,
+	}
+	for _, src := range tests {
+		nodes, err := ParseFragment(strings.NewReader(src))
+		if err != nil {
+			t.Errorf("ParseFragment(%q): %v", src, err)
+			continue
+		}
+		if got := renderAll(t, nodes); got != src {
+			t.Errorf("round trip mismatch:\ngot  %s\nwant %s", got, src)
+		}
+	}
+}
+
+func TestParseDocument(t *testing.T) {
+	src := "<!DOCTYPE html>\n<html lang=\"en-US\"><head><title>Flights</title></head><body><p>Hi</p></body></html>\n"
+	nodes, err := Parse(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) != 1 {
+		t.Fatalf("got %d roots, want 1", len(nodes))
+	}
+	want := `<!DOCTYPE html><html lang="en-US"><head><title>Flights</title></head><body><p>Hi</p></body></html>`
+	if got := renderAll(t, nodes); got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
+
+func TestParseBuildsElements(t *testing.T) {
+	nodes, err := ParseFragment(strings.NewReader(`<P ID=x Hidden>Hi <B>there</B></P>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	el, ok := nodes[0].(Element)
+	if !ok {
+		t.Fatalf("got %T, want Element", nodes[0])
+	}
+	if el.tag != "p" || len(el.children) != 2 {
+		t.Fatalf("got tag %q with %d children", el.tag, len(el.children))
+	}
+	if _, ok := el.children[0].(TextContent); !ok {
+		t.Errorf("first child is %T, want TextContent", el.children[0])
+	}
+	if got, want := el.Render(), `<p id="x" hidden>Hi <b>there</b></p>`; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestParseOptionalEndTags(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`<ul><li>one<li>two</ul>`, `<ul><li>one</li><li>two</li></ul>`},
+		{`<p>one<p>two<div>three</div>`, `<p>one</p><p>two</p><div>three</div>`},
+		{`<dl><dt>From<dd>SFO<dt>To<dd>EWR</dl>`, `<dl><dt>From</dt><dd>SFO</dd><dt>To</dt><dd>EWR</dd></dl>`},
+		{`<table><tr><td>a<td>b<tr><td>c</table>`, `<table><tr><td>a</td><td>b</td></tr><tr><td>c</td></tr></table>`},
+		{`<select><option>a<option>b</select>`, `<select><option>a</option><option>b</option></select>`},
+		{`<br/><br></br><flight-card />`, `<br><br><flight-card></flight-card>`},
+		{`<p>unclosed`, `<p>unclosed</p>`},
+	}
+	for _, tt := range tests {
+		nodes, err := ParseFragment(strings.NewReader(tt.src))
+		if err != nil {
+			t.Errorf("ParseFragment(%q): %v", tt.src, err)
+			continue
+		}
+		if got := renderAll(t, nodes); got != tt.want {
+			t.Errorf("ParseFragment(%q):\ngot  %s\nwant %s", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		src        string
+		line, col  int
+		msgContain string
+	}{
+		{"<div>\n  <span>x</div>", 2, 10, "expected </span>"},
+		{"<div>\n<section>", 2, 1, "unclosed <section>"},
+		{"<p>ok</p>\n</div>", 2, 1, "no open <div>"},
+		{"<a href=\"x>", 1, 9, "unterminated value"},
+		{"<!-- never closed", 1, 1, "unterminated comment"},
+		{"<div\n  class=x", 1, 1, "unterminated start tag"},
+		{"<!DOCTYPE html><p></p>", 1, 1, "doctype in fragment"},
+		{"<script>alert(1)", 1, 1, "unclosed <script>"},
+	}
+	for _, tt := range tests {
+		_, err := ParseFragment(strings.NewReader(tt.src))
+		var perr *ParseError
+		if !errors.As(err, &perr) {
+			t.Errorf("ParseFragment(%q) error = %v, want *ParseError", tt.src, err)
+			continue
+		}
+		if perr.Line != tt.line || perr.Column != tt.col || !strings.Contains(perr.Msg, tt.msgContain) {
+			t.Errorf("ParseFragment(%q) = %v, want %d:%d containing %q", tt.src, perr, tt.line, tt.col, tt.msgContain)
+		}
+	}
+}
+
+func TestParseRawTextKeepsBytes(t *testing.T) {
+	tests := []struct {
+		src, want string
+	}{
+		{"<script>" + strings.Repeat("\xff", 20) + "</script>", "<script>" + strings.Repeat("\xff", 20) + "</script>"},
+		{"<textarea>" + strings.Repeat("\xff", 20) + "</TEXTAREA>", "<textarea>" + strings.Repeat("\xff", 20) + "</textarea>"},
+		{"<script>İİİİİİİİ</script>", "<script>İİİİİİİİ</script>"},
+		{"<title>Straße İstanbul</Title>", "<title>Straße İstanbul</title>"},
+	}
+	for _, tt := range tests {
+		nodes, err := ParseFragment(strings.NewReader(tt.src))
+		if err != nil {
+			t.Errorf("ParseFragment(%q): %v", tt.src, err)
+			continue
+		}
+		if got := renderAll(t, nodes); got != tt.want {
+			t.Errorf("ParseFragment(%q) = %q, want %q", tt.src, got, tt.want)
+		}
+	}
+}
+
+func FuzzParse(f *testing.F) {
+	for _, seed := range []string{
+		"<div class=a><p>x<br>y</div>",
+		"<!DOCTYPE html><html><head><title>t</title></head><body></body></html>",
+		"<script>" + strings.Repeat("\xff", 20) + "</script>",
+		"<textarea>İİİİ</textarea>",
+		"<ul><li>a<li>b</ul></ul>",
+		"<a href=\"x>",
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, src string) {
+		if nodes, err := Parse(strings.NewReader(src)); err == nil {
+			Fragment(nodes).Render()
+		}
+		if nodes, err := ParseFragment(strings.NewReader(src)); err == nil {
+			Fragment(nodes).Render()
+		}
+		nodes, err := ParseFragmentLenient(strings.NewReader(src))
+		if err != nil {
+			t.Fatalf("ParseFragmentLenient(%q): %v", src, err)
+		}
+		Fragment(nodes).Render()
+	})
+}
+
+func TestParseFragmentLenient(t *testing.T) {
+	tests := []struct {
+		src, want string
+	}{
+		{"<div>\n  <span>x</div>", "<div>\n  <span>x</span></div>"},
+		{"<div>\n<section>", "<div>\n<section></section></div>"},
+		{"<p>ok</p>\n</div>", "<p>ok</p>"},
+		{"<a href=\"x>", "&lt;a href=&#34;x&gt;"},
+		{"<!-- never closed", "&lt;!-- never closed"},
+		{"<!DOCTYPE html><p></p>", "&lt;!DOCTYPE html&gt;<p></p>"},
+		{"<ul><li>a<li>b</ul></ul>", "<ul><li>a</li><li>b</li></ul>"},
+	}
+	for _, tt := range tests {
+		nodes, err := ParseFragmentLenient(strings.NewReader(tt.src))
+		if err != nil {
+			t.Errorf("ParseFragmentLenient(%q): %v", tt.src, err)
+			continue
+		}
+		if got := renderAll(t, nodes); got != tt.want {
+			t.Errorf("ParseFragmentLenient(%q) = %q, want %q", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestParseFragmentLenientScalesLinearly(t *testing.T) {
+	// Each of these leaves something unterminated, which lenient parsing keeps as text
+	// before trying again after the "<". Retrying must not read the rest of the input
+	// each time, or Sanitize would take minutes on a few hundred kilobytes.
+	for _, unit := range []string{`<a href="`, "<!--", "<script>", "<textarea>", "<a x=y ", "<![CDATA[", "<div></x>"} {
+		src := strings.Repeat(unit, 50000)
+		start := time.Now()
+		if _, err := ParseFragmentLenient(strings.NewReader(src)); err != nil {
+			t.Fatal(err)
+		}
+		if d := time.Since(start); d > 2*time.Second {
+			t.Errorf("parsing %d × %q took %v", 50000, unit, d)
+		}
+	}
+}
@@ -0,0 +1,446 @@
+package htma
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Query returns the elements in the tree rooted at r that match a CSS selector,
+// in document order. The root itself is included if it matches.
+//
+// Supported syntax: type and universal selectors, #id, .class, attribute selectors
+// ([a], [a=v], [a~=v], [a|=v], [a^=v], [a$=v], [a*=v]), the descendant, child (>),
+// next-sibling (+) and subsequent-sibling (~) combinators, selector lists (a, b), and the
+// :first-child, :last-child, :only-child, :nth-child() and :nth-last-child() pseudo-classes.
+// Query panics if the selector is invalid.
+func Query(r Renderable, selector string) []Element {
+	list := mustParseSelector(selector)
+	var found []Element
+	for _, n := range indexTree(r) {
+		if list.matches(n) {
+			found = append(found, n.el)
+		}
+	}
+	return found
+}
+
+// QueryOne returns the first element in document order that matches the selector.
+func QueryOne(r Renderable, selector string) (Element, bool) {
+	list := mustParseSelector(selector)
+	for _, n := range indexTree(r) {
+		if list.matches(n) {
+			return n.el, true
+		}
+	}
+	return Element{}, false
+}
+
+func mustParseSelector(selector string) selectorList {
+	list, err := parseSelector(selector)
+	if err != nil {
+		panic(fmt.Sprintf("htma: invalid selector %q: %v", selector, err))
+	}
+	return list
+}
+
+// queryNode is an element together with the position information selectors need.
+type queryNode struct {
+	el       Element
+	parent   *queryNode
+	siblings []*queryNode // element children of parent, including this node
+	index    int          // position in siblings
+}
+
+// indexTree lists the elements of the tree rooted at r in document order.
+func indexTree(r Renderable) []*queryNode {
+	var all []*queryNode
+	var visit func(children []Renderable, parent *queryNode)
+	visit = func(children []Renderable, parent *queryNode) {
+		var siblings []*queryNode
+		for _, c := range flatten(nil, children) {
+			if e, ok := c.(Element); ok {
+				siblings = append(siblings, &queryNode{el: e, parent: parent})
+			}
+		}
+		for i, n := range siblings {
+			n.siblings = siblings
+			n.index = i
+			all = append(all, n)
+			visit(n.el.children, n)
+		}
+	}
+	visit([]Renderable{r}, nil)
+	return all
+}
+
+// selectorList is a comma-separated list of complex selectors.
+type selectorList []complexSelector
+
+func (l selectorList) matches(n *queryNode) bool {
+	for _, c := range l {
+		if c.matches(n, len(c)-1) {
+			return true
+		}
+	}
+	return false
+}
+
+// complexSelector is a chain of compound selectors joined by combinators.
+// The combinator of each part links it to the part before it.
+type complexSelector []selectorPart
+
+type selectorPart struct {
+	combinator byte // ' ', '>', '+' or '~'; unused for the first part
+	compound   compoundSelector
+}
+
+func (c complexSelector) matches(n *queryNode, i int) bool {
+	if !c[i].compound.matches(n) {
+		return false
+	}
+	if i == 0 {
+		return true
+	}
+	switch c[i].combinator {
+	case '>':
+		return n.parent != nil && c.matches(n.parent, i-1)
+	case '+':
+		return n.index > 0 && c.matches(n.siblings[n.index-1], i-1)
+	case '~':
+		for j := n.index - 1; j >= 0; j-- {
+			if c.matches(n.siblings[j], i-1) {
+				return true
+			}
+		}
+	default:
+		for p := n.parent; p != nil; p = p.parent {
+			if c.matches(p, i-1) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// compoundSelector is a sequence of simple selectors that must all match one element.
+type compoundSelector struct {
+	tag     string // empty or "*" for any
+	id      string
+	classes []string
+	attrs   []attrSelector
+	pseudos []pseudoSelector
+}
+
+func (s compoundSelector) matches(n *queryNode) bool {
+	e := n.el
+	if s.tag != "" && s.tag != "*" && s.tag != e.tag {
+		return false
+	}
+	if s.id != "" {
+		if id, _ := e.getAttr("id"); id != s.id {
+			return false
+		}
+	}
+	for _, cls := range s.classes {
+		if !e.HasClass(cls) {
+			return false
+		}
+	}
+	for _, a := range s.attrs {
+		if !a.matches(e) {
+			return false
+		}
+	}
+	for _, p := range s.pseudos {
+		if !p.matches(n) {
+			return false
+		}
+	}
+	return true
+}
+
+type attrSelector struct {
+	name  string
+	op    string // "", "=", "~=", "|=", "^=", "$=" or "*="
+	value string
+}
+
+func (s attrSelector) matches(e Element) bool {
+	v, ok := e.getAttr(s.name)
+	if !ok {
+		return false
+	}
+	switch s.op {
+	case "":
+		return true
+	case "=":
+		return v == s.value
+	case "~=":
+		for _, f := range strings.Fields(v) {
+			if f == s.value {
+				return true
+			}
+		}
+		return false
+	case "|=":
+		return v == s.value || strings.HasPrefix(v, s.value+"-")
+	case "^=":
+		return s.value != "" && strings.HasPrefix(v, s.value)
+	case "$=":
+		return s.value != "" && strings.HasSuffix(v, s.value)
+	case "*=":
+		return s.value != "" && strings.Contains(v, s.value)
+	}
+	return false
+}
+
+// pseudoSelector matches elements whose 1-based position, counted from the start or
+// the end of their siblings, is a*k+b for some k >= 0.
+type pseudoSelector struct {
+	fromEnd bool
+	a, b    int
+}
+
+func (p pseudoSelector) matches(n *queryNode) bool {
+	pos := n.index + 1
+	if p.fromEnd {
+		pos = len(n.siblings) - n.index
+	}
+	if p.a == 0 {
+		return pos == p.b
+	}
+	k := pos - p.b
+	return k%p.a == 0 && k/p.a >= 0
+}
+
+// selectorParser is a recursive-descent parser for the supported selector grammar.
+type selectorParser struct {
+	s   string
+	pos int
+}
+
+func parseSelector(s string) (selectorList, error) {
+	p := &selectorParser{s: s}
+	var list selectorList
+	for {
+		c, err := p.complex()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, c)
+		p.skipSpace()
+		if p.pos == len(p.s) {
+			return list, nil
+		}
+		if p.s[p.pos] != ',' {
+			return nil, fmt.Errorf("unexpected %q at offset %d", p.s[p.pos], p.pos)
+		}
+		p.pos++
+	}
+}
+
+func (p *selectorParser) skipSpace() bool {
+	start := p.pos
+	for p.pos < len(p.s) && isSpace(p.s[p.pos]) {
+		p.pos++
+	}
+	return p.pos > start
+}
+
+func (p *selectorParser) complex() (complexSelector, error) {
+	p.skipSpace()
+	var c complexSelector
+	combinator := byte(' ')
+	for {
+		compound, err := p.compound()
+		if err != nil {
+			return nil, err
+		}
+		c = append(c, selectorPart{combinator: combinator, compound: compound})
+
+		space := p.skipSpace()
+		if p.pos == len(p.s) || p.s[p.pos] == ',' {
+			return c, nil
+		}
+		switch p.s[p.pos] {
+		case '>', '+', '~':
+			combinator = p.s[p.pos]
+			p.pos++
+			p.skipSpace()
+		default:
+			if !space {
+				return nil, fmt.Errorf("unexpected %q at offset %d", p.s[p.pos], p.pos)
+			}
+			combinator = ' '
+		}
+	}
+}
+
+func (p *selectorParser) compound() (compoundSelector, error) {
+	var s compoundSelector
+	start := p.pos
+	if p.pos < len(p.s) && p.s[p.pos] == '*' {
+		s.tag = "*"
+		p.pos++
+	} else if name := p.ident(); name != "" {
+		s.tag = strings.ToLower(name)
+	}
+	for p.pos < len(p.s) {
+		switch p.s[p.pos] {
+		case '#':
+			p.pos++
+			if s.id = p.ident(); s.id == "" {
+				return s, fmt.Errorf("missing id at offset %d", p.pos)
+			}
+		case '.':
+			p.pos++
+			cls := p.ident()
+			if cls == "" {
+				return s, fmt.Errorf("missing class name at offset %d", p.pos)
+			}
+			s.classes = append(s.classes, cls)
+		case '[':
+			a, err := p.attr()
+			if err != nil {
+				return s, err
+			}
+			s.attrs = append(s.attrs, a)
+		case ':':
+			ps, err := p.pseudo()
+			if err != nil {
+				return s, err
+			}
+			s.pseudos = append(s.pseudos, ps...)
+		default:
+			if p.pos == start {
+				return s, fmt.Errorf("expected selector at offset %d", p.pos)
+			}
+			return s, nil
+		}
+	}
+	if p.pos == start {
+		return s, fmt.Errorf("expected selector at offset %d", p.pos)
+	}
+	return s, nil
+}
+
+func (p *selectorParser) ident() string {
+	start := p.pos
+	for p.pos < len(p.s) {
+		c := p.s[p.pos]
+		if !(isASCIILetter(c) || '0' <= c && c <= '9' || c == '-' || c == '_' || c >= 0x80) {
+			break
+		}
+		p.pos++
+	}
+	return p.s[start:p.pos]
+}
+
+func (p *selectorParser) attr() (attrSelector, error) {
+	var a attrSelector
+	p.pos++ // [
+	p.skipSpace()
+	if a.name = strings.ToLower(p.ident()); a.name == "" {
+		return a, fmt.Errorf("missing attribute name at offset %d", p.pos)
+	}
+	p.skipSpace()
+	if p.pos < len(p.s) && p.s[p.pos] == ']' {
+		p.pos++
+		return a, nil
+	}
+	for _, op := range []string{"=", "~=", "|=", "^=", "$=", "*="} {
+		if strings.HasPrefix(p.s[p.pos:], op) {
+			a.op = op
+			p.pos += len(op)
+			break
+		}
+	}
+	if a.op == "" {
+		return a, fmt.Errorf("invalid attribute selector at offset %d", p.pos)
+	}
+	p.skipSpace()
+	if p.pos < len(p.s) && (p.s[p.pos] == '"' || p.s[p.pos] == '\'') {
+		q := p.s[p.pos]
+		end := strings.IndexByte(p.s[p.pos+1:], q)
+		if end < 0 {
+			return a, fmt.Errorf("unterminated string at offset %d", p.pos)
+		}
+		a.value = p.s[p.pos+1 : p.pos+1+end]
+		p.pos += end + 2
+	} else {
+		start := p.pos
+		for p.pos < len(p.s) && p.s[p.pos] != ']' && !isSpace(p.s[p.pos]) {
+			p.pos++
+		}
+		a.value = p.s[start:p.pos]
+	}
+	p.skipSpace()
+	if p.pos >= len(p.s) || p.s[p.pos] != ']' {
+		return a, fmt.Errorf("missing ] at offset %d", p.pos)
+	}
+	p.pos++
+	return a, nil
+}
+
+func (p *selectorParser) pseudo() ([]pseudoSelector, error) {
+	p.pos++ // :
+	name := strings.ToLower(p.ident())
+	first := pseudoSelector{b: 1}
+	last := pseudoSelector{fromEnd: true, b: 1}
+	switch name {
+	case "first-child":
+		return []pseudoSelector{first}, nil
+	case "last-child":
+		return []pseudoSelector{last}, nil
+	case "only-child":
+		return []pseudoSelector{first, last}, nil
+	case "nth-child", "nth-last-child":
+		if p.pos >= len(p.s) || p.s[p.pos] != '(' {
+			return nil, fmt.Errorf("missing ( after :%s", name)
+		}
+		end := strings.IndexByte(p.s[p.pos:], ')')
+		if end < 0 {
+			return nil, fmt.Errorf("missing ) after :%s", name)
+		}
+		a, b, err := parseNth(p.s[p.pos+1 : p.pos+end])
+		if err != nil {
+			return nil, err
+		}
+		p.pos += end + 1
+		return []pseudoSelector{{fromEnd: name == "nth-last-child", a: a, b: b}}, nil
+	}
+	return nil, fmt.Errorf("unsupported pseudo-class :%s", name)
+}
+
+// parseNth parses the an+b argument of :nth-child.
+func parseNth(s string) (a, b int, err error) {
+	s = strings.ToLower(strings.ReplaceAll(s, " ", ""))
+	switch s {
+	case "odd":
+		return 2, 1, nil
+	case "even":
+		return 2, 0, nil
+	}
+	n := strings.IndexByte(s, 'n')
+	if n < 0 {
+		b, err = strconv.Atoi(s)
+		return 0, b, err
+	}
+	switch coef := s[:n]; coef {
+	case "", "+":
+		a = 1
+	case "-":
+		a = -1
+	default:
+		if a, err = strconv.Atoi(coef); err != nil {
+			return 0, 0, fmt.Errorf("invalid :nth-child argument %q", s)
+		}
+	}
+	if rest := s[n+1:]; rest != "" {
+		if b, err = strconv.Atoi(rest); err != nil {
+			return 0, 0, fmt.Errorf("invalid :nth-child argument %q", s)
+		}
+	}
+	return a, b, nil
+}
@@ -0,0 +1,114 @@
+package htma
+
+import (
+	"strings"
+	"testing"
+)
+
+func queryPage() Element {
+	return Main().IDAttr("main").AddChild(
+		SearchCard().ClassAttr("card").AddChild(
+			Form().ActionAttr("/flights").AddChild(
+				Input().NameAttr("from").ClassAttr("field"),
+				Input().NameAttr("to").ClassAttr("field wide").RequiredAttr(true),
+				MdFilledButton().IDAttr("search").DataOnClickAttr("@get('/flights')").Text("Search"),
+			),
+		),
+		ResultsCard().ClassAttr("card results").AddChild(
+			FlightCard().IDAttr("UA1").IdentAttr("UA1").StatusClassAttr("on-time"),
+			FlightCard().IDAttr("UA2").IdentAttr("UA2").StatusClassAttr("delayed"),
+			FlightCard().IDAttr("DL3").IdentAttr("DL3").StatusClassAttr("delayed-long"),
+			FlightCard().IDAttr("AA4").IdentAttr("AA4"),
+		),
+		Footer().AddChild(
+			A().HrefAttr("https://example.com").Text("Partner"),
+			Content(" | "),
+			A().HrefAttr("/about").Text("About"),
+		),
+	)
+}
+
+// ids returns the id, or failing that the tag, of each element.
+func ids(els []Element) string {
+	var out []string
+	for _, e := range els {
+		if id, ok := e.GetAttr("id"); ok {
+			out = append(out, id)
+		} else if name, ok := e.GetAttr("name"); ok {
+			out = append(out, name)
+		} else {
+			out = append(out, e.Tag())
+		}
+	}
+	return strings.Join(out, " ")
+}
+
+func TestQuery(t *testing.T) {
+	page := queryPage()
+	tests := []struct {
+		selector string
+		want     string
+	}{
+		{"flight-card", "UA1 UA2 DL3 AA4"},
+		{"#UA2", "UA2"},
+		{"main", "main"},
+		{".card", "search-card results-card"},
+		{".card.results > flight-card:first-child", "UA1"},
+		{"input.field", "from to"},
+		{"input.wide[required]", "to"},
+		{"[data-on-click]", "search"},
+		{"md-filled-button", "search"},
+		{"a[href^=http]", "a"},
+		{`a[href$="about"]`, "a"},
+		{"[status-class*=delay]", "UA2 DL3"},
+		{"[status-class|=delayed]", "UA2 DL3"},
+		{"[status-class='delayed']", "UA2"},
+		{"[class~=wide]", "to"},
+		{"search-card input", "from to"},
+		{"main > input", ""},
+		{"main > *", "search-card results-card footer"},
+		{"#UA1 + flight-card", "UA2"},
+		{"#UA2 ~ flight-card", "DL3 AA4"},
+		{"flight-card:nth-child(2)", "UA2"},
+		{"flight-card:nth-child(odd)", "UA1 DL3"},
+		{"flight-card:nth-child(2n)", "UA2 AA4"},
+		{"flight-card:nth-child(-n+2)", "UA1 UA2"},
+		{"flight-card:nth-last-child(1)", "AA4"},
+		{"footer a:last-child", "a"},
+		{"footer > a:first-child[href^=https]", "a"},
+		{"results-card:only-child", ""},
+		{"#search, #UA1", "search UA1"},
+		{"section", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			if got := ids(Query(page, tt.selector)); got != tt.want {
+				t.Errorf("Query(%q) = %q, want %q", tt.selector, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryOne(t *testing.T) {
+	page := queryPage()
+	e, ok := QueryOne(page, "results-card flight-card[status-class^=delayed]")
+	if !ok || ids([]Element{e}) != "UA2" {
+		t.Errorf("QueryOne = %q, %v", e.Render(), ok)
+	}
+	if _, ok := QueryOne(page, "table"); ok {
+		t.Error("QueryOne matched a missing element")
+	}
+}
+
+func TestQueryInvalidSelectorPanics(t *testing.T) {
+	for _, selector := range []string{"", "div >", "[href", "a:hover", "#", "div,", ":nth-child(x)"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Query(%q) did not panic", selector)
+				}
+			}()
+			Query(Div(), selector)
+		}()
+	}
+}
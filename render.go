@@ -0,0 +1,167 @@
+package htma
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Options controls how a tree is serialized.
+type Options struct {
+	// Indent, when non-empty, places each block-level child on its own line,
+	// prefixed with one Indent per level of nesting. Text, inline elements and
+	// whitespace-sensitive elements such as pre, textarea, script and style are
+	// written exactly as in compact mode.
+	Indent string
+
+	// Nonce, when non-empty, is added as the nonce attribute of every script and
+	// style element, and of links preloading scripts, that does not already have one.
+	// When rendering with a context, a nonce stored with WithNonce is used if Nonce is empty.
+	Nonce string
+
+	// Lenient, when true, drops attributes whose names fail ValidateAttrName and writes
+	// the content of elements whose tags fail ValidateTagName without the tags. By
+	// default such names make rendering fail with an error wrapping ErrInvalidName,
+	// before any of the element is written.
+	Lenient bool
+}
+
+type optionsKey struct{}
+
+// WithOptions returns a copy of ctx carrying render options. RenderContext, Handler
+// and SSE render with the options of their context, so middleware can set them for
+// every page and event of a request. Options given directly, as to RenderStreamWith,
+// are used in place of the context's, never merged with them.
+func WithOptions(ctx context.Context, opts Options) context.Context {
+	return context.WithValue(ctx, optionsKey{}, opts)
+}
+
+// whitespaceSensitiveTags lists elements whose content must never be reformatted.
+var whitespaceSensitiveTags = map[string]bool{
+	"pre":      true,
+	"textarea": true,
+	"script":   true,
+	"style":    true,
+}
+
+// blockTags lists elements that the browser lays out as blocks, or does not display,
+// so whitespace between them is not visible on the page. Any other element, including
+// custom elements, which are inline by default, is treated as inline.
+var blockTags = map[string]bool{
+	"address": true, "article": true, "aside": true, "base": true, "blockquote": true,
+	"body": true, "caption": true, "col": true, "colgroup": true, "dd": true,
+	"details": true, "dialog": true, "div": true, "dl": true, "dt": true,
+	"fieldset": true, "figcaption": true, "figure": true, "footer": true, "form": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"head": true, "header": true, "hgroup": true, "hr": true, "html": true,
+	"legend": true, "li": true, "link": true, "main": true, "menu": true, "meta": true,
+	"nav": true, "noscript": true, "ol": true, "optgroup": true, "option": true,
+	"p": true, "pre": true, "script": true, "search": true, "section": true,
+	"source": true, "style": true, "summary": true, "table": true, "tbody": true,
+	"td": true, "template": true, "tfoot": true, "th": true, "thead": true,
+	"title": true, "tr": true, "track": true, "ul": true,
+}
+
+// renderer carries the output, options and context through a single render pass.
+type renderer struct {
+	ctx   context.Context
+	w     io.Writer
+	opts  Options
+	depth int
+}
+
+// nodeRenderer is implemented by nodes that take part in option-aware rendering.
+// Other Renderables are written with their own RenderStream method.
+type nodeRenderer interface {
+	renderNode(r *renderer) error
+}
+
+// optionsFrom returns the options stored in ctx with WithOptions, if any.
+func optionsFrom(ctx context.Context) Options {
+	opts, _ := ctx.Value(optionsKey{}).(Options)
+	return opts
+}
+
+// newRenderer starts a render pass with opts. Callers that render with a context
+// and no options of their own pass optionsFrom(ctx).
+func newRenderer(ctx context.Context, w io.Writer, opts Options) *renderer {
+	if opts.Nonce == "" {
+		opts.Nonce = NonceFromContext(ctx)
+	}
+	return &renderer{ctx: ctx, w: w, opts: opts}
+}
+
+func (r *renderer) pretty() bool {
+	return r.opts.Indent != ""
+}
+
+func (r *renderer) write(s string) error {
+	_, err := io.WriteString(r.w, s)
+	return err
+}
+
+// newline starts a new line indented to the current depth.
+func (r *renderer) newline() error {
+	return r.write("\n" + strings.Repeat(r.opts.Indent, r.depth))
+}
+
+// renderContext writes r to w, passing ctx along if r accepts a context.
+func renderContext(ctx context.Context, w io.Writer, r Renderable) error {
+	if cr, ok := r.(ContextRenderer); ok {
+		return cr.RenderContext(ctx, w)
+	}
+	return r.RenderStream(w)
+}
+
+// renderChild renders c, stopping first if the context has been cancelled.
+func (r *renderer) renderChild(c Renderable) error {
+	if err := r.ctx.Err(); err != nil {
+		return err
+	}
+	switch c := c.(type) {
+	case nodeRenderer:
+		return c.renderNode(r)
+	case ContextRenderer:
+		return c.RenderContext(r.ctx, r.w)
+	}
+	return c.RenderStream(r.w)
+}
+
+// isBlockElement reports whether n is a block-level element.
+func isBlockElement(n Renderable) bool {
+	e, ok := n.(Element)
+	return ok && blockTags[e.tag]
+}
+
+// renderChildAt renders siblings[i]. If it is an element and fails, its position
+// among its siblings is added to the front of the error path.
+func (r *renderer) renderChildAt(siblings []Renderable, i int) error {
+	err := r.renderChild(siblings[i])
+	if err == nil {
+		return nil
+	}
+	if _, ok := siblings[i].(Element); !ok {
+		return err
+	}
+	return wrapRenderError(err, pathSegment(siblings, i))
+}
+
+// pathSegment names siblings[i] by its tag, followed by its 1-based position among
+// siblings with the same tag when there is more than one.
+func pathSegment(siblings []Renderable, i int) string {
+	tag := siblings[i].(Element).tag
+	n, pos := 0, 0
+	for j, s := range siblings {
+		if e, ok := s.(Element); ok && e.tag == tag {
+			n++
+			if j == i {
+				pos = n
+			}
+		}
+	}
+	if n == 1 {
+		return tag
+	}
+	return fmt.Sprintf("%s[%d]", tag, pos)
+}
@@ -0,0 +1,179 @@
+package htma
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// The Safe types mark strings that are trusted in a particular context. Each can
+// only be created through a constructor that validates the value, or through an
+// Unchecked constructor that vouches for it, so every place that bypasses
+// escaping can be found by searching for "Unchecked".
+
+// SafeHTML is markup trusted to be rendered without escaping.
+type SafeHTML struct {
+	s string
+}
+
+// SanitizedHTML sanitizes untrusted markup with policy and returns the result as SafeHTML.
+func SanitizedHTML(html string, policy Policy) SafeHTML {
+	return SafeHTML{Sanitize(html, policy).Render()}
+}
+
+// UncheckedHTML marks html as trusted without checking it.
+// It must never contain user-controlled content.
+func UncheckedHTML(html string) SafeHTML {
+	return SafeHTML{html}
+}
+
+// String returns the markup.
+func (h SafeHTML) String() string {
+	return h.s
+}
+
+// Render returns the markup as is.
+func (h SafeHTML) Render() string {
+	return h.s
+}
+
+// RenderStream writes the markup to a writer as is.
+func (h SafeHTML) RenderStream(w io.Writer) error {
+	_, err := io.WriteString(w, h.s)
+	return err
+}
+
+// SafeURL is a URL trusted to be navigated to or loaded.
+type SafeURL struct {
+	s string
+}
+
+// ErrUnsafeURL is returned by ParseSafeURL for URLs with a disallowed scheme.
+var ErrUnsafeURL = errors.New("htma: unsafe URL scheme")
+
+// ParseSafeURL returns u as a SafeURL if it is relative or uses the http, https,
+// mailto or tel scheme, the same rule applied to URL attributes at render time.
+func ParseSafeURL(u string) (SafeURL, error) {
+	if sanitizeURL(u) != u {
+		return SafeURL{}, fmt.Errorf("%w: %q", ErrUnsafeURL, u)
+	}
+	return SafeURL{u}, nil
+}
+
+// UncheckedURL marks u as trusted without checking its scheme, for URLs such as
+// data: images that the render-time check would reject.
+func UncheckedURL(u string) SafeURL {
+	return SafeURL{u}
+}
+
+// String returns the URL.
+func (u SafeURL) String() string {
+	return u.s
+}
+
+// SafeCSS is a CSS declaration list trusted for use in a style attribute.
+type SafeCSS struct {
+	s string
+}
+
+// ErrUnsafeCSS is returned by ParseSafeCSS for CSS that could run code or load resources.
+var ErrUnsafeCSS = errors.New("htma: unsafe CSS")
+
+// ParseSafeCSS returns css as SafeCSS if it contains no expressions, url()
+// references, imports, escapes or markup.
+func ParseSafeCSS(css string) (SafeCSS, error) {
+	lower := strings.ToLower(css)
+	for _, bad := range unsafeCSS {
+		if strings.Contains(lower, bad) {
+			return SafeCSS{}, fmt.Errorf("%w: contains %q", ErrUnsafeCSS, bad)
+		}
+	}
+	if strings.ContainsAny(css, "<>") {
+		return SafeCSS{}, fmt.Errorf("%w: contains markup", ErrUnsafeCSS)
+	}
+	return SafeCSS{css}, nil
+}
+
+// UncheckedCSS marks css as trusted without checking it.
+func UncheckedCSS(css string) SafeCSS {
+	return SafeCSS{css}
+}
+
+// String returns the CSS.
+func (c SafeCSS) String() string {
+	return c.s
+}
+
+// SafeJS is JavaScript trusted to run on the page.
+type SafeJS struct {
+	s string
+}
+
+// SafeJSValue encodes v as a JavaScript literal. The JSON encoding escapes <, > and &,
+// so the result is safe inside a script element.
+func SafeJSValue(v any) (SafeJS, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return SafeJS{}, err
+	}
+	return SafeJS{string(b)}, nil
+}
+
+// UncheckedJS marks js as trusted without checking it.
+// It must never contain user-controlled content.
+func UncheckedJS(js string) SafeJS {
+	return SafeJS{js}
+}
+
+// String returns the code.
+func (j SafeJS) String() string {
+	return j.s
+}
+
+// URLAttr sets a URL attribute from a trusted value, which is not sanitized when rendered.
+// key must be one of the URL attributes the renderer sanitizes: href, src, action,
+// formaction or poster. URLAttr panics for any other attribute, as a SafeURL written
+// to an attribute such as onclick would run as code.
+func (e Element) URLAttr(key string, u SafeURL) Element {
+	if !urlAttrs[key] {
+		panic(fmt.Sprintf("htma: URLAttr: %q is not a URL attribute", key))
+	}
+	return e.setAttribute(attribute{key: key, value: u.s, trusted: true})
+}
+
+// HrefSafeAttr sets href from a trusted URL.
+func (e Element) HrefSafeAttr(u SafeURL) Element {
+	return e.URLAttr("href", u)
+}
+
+// SrcSafeAttr sets src from a trusted URL.
+func (e Element) SrcSafeAttr(u SafeURL) Element {
+	return e.URLAttr("src", u)
+}
+
+// ActionSafeAttr sets action from a trusted URL.
+func (e Element) ActionSafeAttr(u SafeURL) Element {
+	return e.URLAttr("action", u)
+}
+
+// FormActionSafeAttr sets formaction from a trusted URL.
+func (e Element) FormActionSafeAttr(u SafeURL) Element {
+	return e.URLAttr("formaction", u)
+}
+
+// PosterSafeAttr sets poster from a trusted URL.
+func (e Element) PosterSafeAttr(u SafeURL) Element {
+	return e.URLAttr("poster", u)
+}
+
+// StyleSafeAttr sets the style attribute from trusted CSS, replacing any existing value.
+func (e Element) StyleSafeAttr(css SafeCSS) Element {
+	return e.setAttr("style", css.s)
+}
+
+// ScriptText sets the code of a script element from trusted JavaScript.
+func (e Element) ScriptText(js SafeJS) Element {
+	return e.Text(js.s)
+}
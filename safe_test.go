@@ -0,0 +1,93 @@
+package htma
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseSafeURL(t *testing.T) {
+	for _, u := range []string{"/flights", "https://example.com", "mailto:ops@example.com", "#top"} {
+		if _, err := ParseSafeURL(u); err != nil {
+			t.Errorf("ParseSafeURL(%q): %v", u, err)
+		}
+	}
+	for _, u := range []string{"javascript:alert(1)", "data:text/html,x", " JAVASCRIPT:x"} {
+		if _, err := ParseSafeURL(u); !errors.Is(err, ErrUnsafeURL) {
+			t.Errorf("ParseSafeURL(%q) error = %v, want ErrUnsafeURL", u, err)
+		}
+	}
+}
+
+func TestSafeURLAttributes(t *testing.T) {
+	logo := UncheckedURL("data:image/png;base64,iVBORw0KGgo=")
+	help, err := ParseSafeURL("/help")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		component Element
+		want      string
+	}{
+		{Img().SrcSafeAttr(logo), `<img src="data:image/png;base64,iVBORw0KGgo=">`},
+		{Img().SrcAttr(logo.String()), `<img src="` + unsafeURL + `">`},
+		{A().HrefSafeAttr(help), `<a href="/help"></a>`},
+		{Form().ActionSafeAttr(help), `<form action="/help"></form>`},
+		{Button().FormActionSafeAttr(help), `<button formaction="/help"></button>`},
+		{Video().PosterSafeAttr(logo), `<video poster="data:image/png;base64,iVBORw0KGgo="></video>`},
+		{A().HrefSafeAttr(logo).HrefAttr("javascript:x"), `<a href="` + unsafeURL + `"></a>`},
+	}
+	for _, tt := range tests {
+		if got := tt.component.Render(); got != tt.want {
+			t.Errorf("got %s, want %s", got, tt.want)
+		}
+	}
+}
+
+func TestURLAttrPanicsForOtherAttributes(t *testing.T) {
+	u, err := ParseSafeURL("alert(1)") // relative, so it passes the scheme check
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"onclick", "style", "data-on-click", "HREF"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("URLAttr(%q) did not panic", key)
+				}
+			}()
+			Button().URLAttr(key, u)
+		}()
+	}
+}
+
+func TestParseSafeCSS(t *testing.T) {
+	css, err := ParseSafeCSS("color: red; margin: 0 auto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := Div().StyleSafeAttr(css).Render(), `<div style="color: red; margin: 0 auto"></div>`; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	for _, bad := range []string{"width: expression(alert(1))", "background: URL(https://x)", "@import 'x'", "color: red</style>", `content: "\3c"`} {
+		if _, err := ParseSafeCSS(bad); !errors.Is(err, ErrUnsafeCSS) {
+			t.Errorf("ParseSafeCSS(%q) error = %v, want ErrUnsafeCSS", bad, err)
+		}
+	}
+}
+
+func TestSafeJSAndHTML(t *testing.T) {
+	data, err := SafeJSValue(map[string]string{"note": "</script><b>"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := Script().ScriptText(UncheckedJS("window.flight = " + data.String() + ";")).Render()
+	if want := `<script>window.flight = {"note":"\u003c/script\u003e\u003cb\u003e"};</script>`; got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+
+	notes := SanitizedHTML(`<p onclick="x()">Window seat <b>please</b></p>`, UGCPolicy())
+	got = Div().AddChild(notes, UncheckedHTML("<hr>")).Render()
+	if want := `<div><p>Window seat <b>please</b></p><hr></div>`; got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
@@ -0,0 +1,169 @@
+package htma
+
+import (
+	"slices"
+	"strings"
+)
+
+// Policy is an allow-list describing which markup Sanitize keeps.
+type Policy struct {
+	// Tags lists the elements to keep. Other elements are removed but their content
+	// is kept, except for elements such as script and iframe, which are removed entirely.
+	Tags []string
+
+	// Attrs lists attributes allowed on every kept element.
+	Attrs []string
+
+	// TagAttrs lists further attributes allowed on specific elements.
+	TagAttrs map[string][]string
+
+	// URLSchemes lists the schemes allowed in URL attributes such as href and src.
+	// Relative URLs are always allowed; attributes with other schemes are removed.
+	URLSchemes []string
+
+	// LinkRel, when non-empty, is set as the rel attribute of every kept a element.
+	LinkRel string
+}
+
+// StrictPolicy keeps only text: every element is removed.
+func StrictPolicy() Policy {
+	return Policy{}
+}
+
+// UGCPolicy keeps the formatting, lists, links, images and tables that are common in
+// user-generated rich text, and marks links as nofollow.
+func UGCPolicy() Policy {
+	return Policy{
+		Tags: []string{
+			"a", "abbr", "b", "blockquote", "br", "caption", "cite", "code", "dd", "del",
+			"dl", "dt", "em", "figcaption", "figure", "h1", "h2", "h3", "h4", "h5", "h6",
+			"hr", "i", "img", "ins", "kbd", "li", "mark", "ol", "p", "pre", "q", "s",
+			"small", "span", "strong", "sub", "sup", "table", "tbody", "td", "tfoot",
+			"th", "thead", "tr", "u", "ul",
+		},
+		Attrs: []string{"title", "lang", "dir"},
+		TagAttrs: map[string][]string{
+			"a":          {"href"},
+			"img":        {"src", "alt", "width", "height"},
+			"blockquote": {"cite"},
+			"q":          {"cite"},
+			"ol":         {"start", "reversed"},
+			"td":         {"colspan", "rowspan"},
+			"th":         {"colspan", "rowspan", "scope"},
+		},
+		URLSchemes: []string{"http", "https", "mailto"},
+		LinkRel:    "nofollow ugc noopener",
+	}
+}
+
+// droppedTags lists elements removed together with their content, since their
+// content is code, an embedded document or a form control rather than readable text.
+var droppedTags = map[string]bool{
+	"script": true, "style": true, "iframe": true, "frame": true, "frameset": true,
+	"object": true, "embed": true, "applet": true, "template": true, "noscript": true,
+	"noembed": true, "textarea": true, "select": true, "title": true, "head": true,
+	"svg": true, "math": true, "xmp": true, "plaintext": true, "noframes": true,
+}
+
+// sanitizedURLAttrs lists attributes checked against Policy.URLSchemes.
+var sanitizedURLAttrs = map[string]bool{
+	"href": true, "src": true, "cite": true, "action": true, "formaction": true,
+	"poster": true, "background": true, "longdesc": true, "usemap": true,
+}
+
+// unsafeCSS lists lowercase fragments that make a style attribute unsafe.
+var unsafeCSS = []string{"expression", "javascript:", "vbscript:", "url(", "@import", "behavior", "-moz-binding", "\\"}
+
+// Sanitize parses untrusted HTML and returns a tree holding only what policy allows.
+// Event handler attributes are always removed, as are URLs with disallowed schemes
+// and style attributes containing expressions, imports or url() references. The
+// input is parsed with ParseFragmentLenient, so stray and missing end tags are
+// tolerated and unparseable markup is kept as escaped text.
+func Sanitize(html string, policy Policy) Renderable {
+	nodes, err := ParseFragmentLenient(strings.NewReader(html))
+	if err != nil {
+		return Content(html)
+	}
+	return Fragment(policy.sanitizeNodes(nodes))
+}
+
+func (p Policy) sanitizeNodes(nodes []Renderable) []Renderable {
+	var out []Renderable
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case TextContent:
+			out = append(out, n)
+		case Element:
+			out = append(out, p.sanitizeElement(n)...)
+		}
+		// Comments and other raw nodes are dropped.
+	}
+	return out
+}
+
+// sanitizeElement returns the nodes that replace e: e itself with its attributes
+// filtered, its content alone if the tag is not allowed, or nothing.
+func (p Policy) sanitizeElement(e Element) []Renderable {
+	if droppedTags[e.tag] {
+		return nil
+	}
+	var content []Renderable
+	if e.text != "" {
+		content = append(content, Content(e.text))
+	}
+	content = append(content, p.sanitizeNodes(e.children)...)
+	if !slices.Contains(p.Tags, e.tag) {
+		return content
+	}
+
+	out := CustomElement(e.tag)
+	for _, a := range e.attrs {
+		if p.allowAttr(e.tag, a) {
+			out = out.setAttribute(a)
+		}
+	}
+	if e.tag == "a" && p.LinkRel != "" {
+		out = out.setAttr("rel", p.LinkRel)
+	}
+	if !out.isVoid {
+		out.children = content
+	}
+	return []Renderable{out}
+}
+
+func (p Policy) allowAttr(tag string, a attribute) bool {
+	key := strings.ToLower(a.key)
+	if strings.HasPrefix(key, "on") {
+		return false
+	}
+	if !slices.Contains(p.Attrs, key) && !slices.Contains(p.TagAttrs[tag], key) {
+		return false
+	}
+	if sanitizedURLAttrs[key] {
+		return p.allowURL(a.value)
+	}
+	if key == "style" {
+		lower := strings.ToLower(a.value)
+		for _, bad := range unsafeCSS {
+			if strings.Contains(lower, bad) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// allowURL reports whether u is relative or uses one of the policy's schemes.
+// It uses the same normalization as the renderer's URL check.
+func (p Policy) allowURL(u string) bool {
+	scheme, ok := urlScheme(u)
+	if !ok {
+		return true
+	}
+	for _, s := range p.URLSchemes {
+		if strings.EqualFold(s, scheme) {
+			return true
+		}
+	}
+	return false
+}
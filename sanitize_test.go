@@ -0,0 +1,93 @@
+package htma
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSanitizeUGC(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"formatting kept", `<p>Great <b>flight</b>, <em>on time</em>!</p>`, `<p>Great <b>flight</b>, <em>on time</em>!</p>`},
+		{"script removed", `<p>hi</p><script>alert(1)</script>`, `<p>hi</p>`},
+		{"event handlers removed", `<p onclick="alert(1)" ONMOUSEOVER="x()" title="t">hi</p>`, `<p title="t">hi</p>`},
+		{"javascript url removed", `<a href="javascript:alert(1)">x</a>`, `<a rel="nofollow ugc noopener">x</a>`},
+		{"obfuscated javascript url removed", `<a href=" java&#09;script:alert(1)">x</a>`, `<a rel="nofollow ugc noopener">x</a>`},
+		{"safe link kept", `<a href="https://example.com/a?b=1&amp;c=2" rel="me">x</a>`, `<a href="https://example.com/a?b=1&amp;c=2" rel="nofollow ugc noopener">x</a>`},
+		{"relative link kept", `<a href="/flights/UA1">UA1</a>`, `<a href="/flights/UA1" rel="nofollow ugc noopener">UA1</a>`},
+		{"data image removed", `<img src="data:image/svg+xml,<svg onload=alert(1)>" alt="x">`, `<img alt="x">`},
+		{"style attribute removed", `<span style="width: expression(alert(1))">x</span>`, `<span>x</span>`},
+		{"unknown tags unwrapped", `<div><font color="red">red</font> text</div>`, `red text`},
+		{"custom elements unwrapped", `<flight-card ident="UA1">UA1</flight-card>`, `UA1`},
+		{"iframe removed", `<iframe src="https://evil.example"></iframe><p>ok</p>`, `<p>ok</p>`},
+		{"comments removed", `<p>a<!-- <script>x</script> -->b</p>`, `<p>ab</p>`},
+		{"text escaped", `<p>1 &lt; 2 &amp; &lt;b&gt;</p>`, `<p>1 &lt; 2 &amp; &lt;b&gt;</p>`},
+		{"unclosed element closed at its parent", `<p>Great flight!<br>Crew was <b>nice</p>`, `<p>Great flight!<br>Crew was <b>nice</b></p>`},
+		{"stray end tag dropped", `Loved it </div> would fly again`, `Loved it  would fly again`},
+		{"open elements closed at end", `<p>Window <em>seat`, `<p>Window <em>seat</em></p>`},
+		{"unterminated tag kept as text", `<p>1 <2 and <b`, `<p>1 &lt;2 and &lt;b</p>`},
+		{"unterminated comment kept as text", `<p>a</p><!-- b`, `<p>a</p>&lt;!-- b`},
+		{"unclosed script kept as text", `<p>hi</p><script>alert(1)`, `<p>hi</p>&lt;script&gt;alert(1)`},
+		{"invalid UTF-8 in textarea", "<textarea>" + strings.Repeat("\xff", 20) + "</textarea><p>ok</p>", "<p>ok</p>"},
+		{"invalid UTF-8 in script", "<script>" + strings.Repeat("\xff", 20) + "</script><p>ok</p>", "<p>ok</p>"},
+		{"non-ASCII script removed", `<script>İİİİİİİİ</script><p>ok</p>`, `<p>ok</p>`},
+		{"non-ASCII end tag case", `<p>İ</p><SCRIPT>alert("İİİİ")</ScRiPt><p>ok</p>`, `<p>İ</p><p>ok</p>`},
+		{"invalid UTF-8 text kept", "<p>\xff\xfe ok</p>", "<p>\xff\xfe ok</p>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Sanitize(tt.input, UGCPolicy()).Render(); got != tt.want {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func FuzzSanitize(f *testing.F) {
+	f.Add(`<p>Great flight!<br>Crew was <b>nice</p>`)
+	f.Add("<textarea>" + strings.Repeat("\xff", 20) + "</textarea>")
+	f.Add(`<a href="javascript:alert(1)" onclick=x>y</a>`)
+	f.Fuzz(func(t *testing.T, src string) {
+		Sanitize(src, UGCPolicy()).Render()
+		Sanitize(src, StrictPolicy()).Render()
+	})
+}
+
+func TestSanitizeStrict(t *testing.T) {
+	got := Sanitize(`<p>Delayed <b>again</b><script>alert(1)</script></p>`, StrictPolicy()).Render()
+	if want := `Delayed again`; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestSanitizeCustomPolicy(t *testing.T) {
+	policy := Policy{
+		Tags:       []string{"span", "a"},
+		TagAttrs:   map[string][]string{"span": {"style", "class"}, "a": {"href"}},
+		URLSchemes: []string{"https"},
+	}
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`<span style="color: red" class="x">a</span>`, `<span style="color: red" class="x">a</span>`},
+		{`<span style="background: url(https://evil.example)">a</span>`, `<span>a</span>`},
+		{`<a href="http://example.com">a</a>`, `<a>a</a>`},
+		{`<a href="mailto:ops@example.com" title="t">a</a>`, `<a>a</a>`},
+	}
+	for _, tt := range tests {
+		if got := Sanitize(tt.input, policy).Render(); got != tt.want {
+			t.Errorf("Sanitize(%q) = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestSanitizeReturnsTree(t *testing.T) {
+	tree := Sanitize(`<ul><li>one</li><li>two</li></ul>`, UGCPolicy())
+	if got := len(Query(tree, "li")); got != 2 {
+		t.Errorf("Query found %d li elements, want 2", got)
+	}
+}
@@ -0,0 +1,48 @@
+package htma
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Signals sets the data-signals attribute to v marshalled as JSON, so the signals
+// follow the json tags of a Go struct. Nested structs and maps become nested signals.
+// Signals panics if v cannot be marshalled.
+func (e Element) Signals(v any) Element {
+	return e.Attr("data-signals", mustMarshalSignals(v))
+}
+
+// SignalsIfMissing is like Signals but sets data-signals__ifmissing, so signals that
+// already exist in the browser keep their values.
+func (e Element) SignalsIfMissing(v any) Element {
+	return e.Attr("data-signals__ifmissing", mustMarshalSignals(v))
+}
+
+func mustMarshalSignals(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		panic(fmt.Sprintf("htma: cannot marshal signals: %v", err))
+	}
+	return string(b)
+}
+
+// ReadSignals decodes the signals Datastar sends with a request into v. GET requests
+// carry them in the datastar query parameter; other requests carry them in the body.
+func ReadSignals(r *http.Request, v any) error {
+	if r.Method == http.MethodGet {
+		data := r.URL.Query().Get("datastar")
+		if data == "" {
+			return errors.New("htma: missing datastar query parameter")
+		}
+		if err := json.Unmarshal([]byte(data), v); err != nil {
+			return fmt.Errorf("htma: reading signals: %w", err)
+		}
+		return nil
+	}
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		return fmt.Errorf("htma: reading signals: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package htma
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+type seatSignals struct {
+	Flight string `json:"flight"`
+	Seat   struct {
+		Row    int    `json:"row"`
+		Letter string `json:"letter"`
+	} `json:"seat"`
+	Note string `json:"note,omitempty"`
+}
+
+func TestSignals(t *testing.T) {
+	var s seatSignals
+	s.Flight = "UA1"
+	s.Seat.Row = 12
+	s.Seat.Letter = "A"
+
+	tests := []struct {
+		component Element
+		want      string
+	}{
+		{Div().Signals(s), `<div data-signals="{&#34;flight&#34;:&#34;UA1&#34;,&#34;seat&#34;:{&#34;row&#34;:12,&#34;letter&#34;:&#34;A&#34;}}"></div>`},
+		{Div().SignalsIfMissing(map[string]bool{"open": false}), `<div data-signals__ifmissing="{&#34;open&#34;:false}"></div>`},
+	}
+	for _, tt := range tests {
+		if got := tt.component.Render(); got != tt.want {
+			t.Errorf("got  %s\nwant %s", got, tt.want)
+		}
+	}
+}
+
+func TestSignalsPanicsOnMarshalError(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Signals did not panic")
+		}
+	}()
+	Div().Signals(func() {})
+}
+
+func TestReadSignals(t *testing.T) {
+	const data = `{"flight":"UA1","seat":{"row":12,"letter":"A"}}`
+	requests := []struct {
+		method, target, body string
+	}{
+		{"GET", "/seats?datastar=" + url.QueryEscape(data), ""},
+		{"POST", "/seats", data},
+		{"DELETE", "/seats", data},
+	}
+	for _, req := range requests {
+		var got seatSignals
+		r := httptest.NewRequest(req.method, req.target, strings.NewReader(req.body))
+		if err := ReadSignals(r, &got); err != nil {
+			t.Fatalf("%s: %v", req.method, err)
+		}
+		if got.Flight != "UA1" || got.Seat.Row != 12 || got.Seat.Letter != "A" {
+			t.Errorf("%s: got %+v", req.method, got)
+		}
+	}
+
+	var got seatSignals
+	if err := ReadSignals(httptest.NewRequest("GET", "/seats", nil), &got); err == nil {
+		t.Error("GET without datastar parameter: no error")
+	}
+	if err := ReadSignals(httptest.NewRequest("POST", "/seats", strings.NewReader("{")), &got); err == nil {
+		t.Error("POST with malformed body: no error")
+	}
+}
@@ -0,0 +1,218 @@
+package htma
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Datastar event types.
+const (
+	EventPatchElements = "datastar-patch-elements"
+	EventPatchSignals  = "datastar-patch-signals"
+)
+
+// PatchMode controls how Datastar merges patched elements into the page.
+type PatchMode string
+
+// Patch modes understood by Datastar. ModeOuter is the default.
+const (
+	ModeOuter   PatchMode = "outer"
+	ModeInner   PatchMode = "inner"
+	ModeReplace PatchMode = "replace"
+	ModePrepend PatchMode = "prepend"
+	ModeAppend  PatchMode = "append"
+	ModeBefore  PatchMode = "before"
+	ModeAfter   PatchMode = "after"
+	ModeRemove  PatchMode = "remove"
+)
+
+// patchOptions holds the settings of a datastar-patch-elements event.
+type patchOptions struct {
+	selector       string
+	mode           PatchMode
+	viewTransition bool
+}
+
+// PatchOption configures a datastar-patch-elements event.
+type PatchOption func(*patchOptions)
+
+// WithSelector targets the elements matching a CSS selector instead of matching by id.
+func WithSelector(selector string) PatchOption {
+	return func(o *patchOptions) { o.selector = selector }
+}
+
+// WithMode sets how the elements are merged into the page.
+func WithMode(mode PatchMode) PatchOption {
+	return func(o *patchOptions) { o.mode = mode }
+}
+
+// WithViewTransition wraps the patch in a view transition where the browser supports it.
+func WithViewTransition() PatchOption {
+	return func(o *patchOptions) { o.viewTransition = true }
+}
+
+// signalOptions holds the settings of a datastar-patch-signals event.
+type signalOptions struct {
+	onlyIfMissing bool
+}
+
+// SignalOption configures a datastar-patch-signals event.
+type SignalOption func(*signalOptions)
+
+// OnlyIfMissing patches only the signals that do not already exist in the browser.
+func OnlyIfMissing() SignalOption {
+	return func(o *signalOptions) { o.onlyIfMissing = true }
+}
+
+// SSE writes Datastar server-sent events to an HTTP response.
+// It is safe for concurrent use; each event is written and flushed atomically.
+type SSE struct {
+	mu  sync.Mutex
+	w   http.ResponseWriter
+	rc  *http.ResponseController
+	ctx context.Context
+	err error // set when the response cannot be flushed
+}
+
+// NewSSE prepares w for an event stream and flushes the headers. Events stop being
+// written once the request's context is cancelled. If w cannot be flushed, as with
+// middleware that wraps the ResponseWriter without Unwrap, no event is written and
+// every send returns the error from flushing.
+func NewSSE(w http.ResponseWriter, r *http.Request) *SSE {
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	if r.ProtoMajor == 1 {
+		h.Set("Connection", "keep-alive")
+	}
+	s := &SSE{w: w, rc: http.NewResponseController(w), ctx: r.Context()}
+	w.WriteHeader(http.StatusOK)
+	if err := s.rc.Flush(); err != nil {
+		s.err = fmt.Errorf("htma: cannot stream events: %w", err)
+	}
+	return s
+}
+
+// Context returns the context of the request the stream belongs to.
+func (s *SSE) Context() context.Context {
+	return s.ctx
+}
+
+// PatchElements sends the rendered markup of r as a datastar-patch-elements event.
+// r is rendered with the request's context.
+func (s *SSE) PatchElements(r Renderable, opts ...PatchOption) error {
+	var o patchOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if err := checkEventField("selector", o.selector); err != nil {
+		return err
+	}
+	if err := checkEventField("mode", string(o.mode)); err != nil {
+		return err
+	}
+	var lines []string
+	if o.selector != "" {
+		lines = append(lines, "selector "+o.selector)
+	}
+	if o.mode != "" && o.mode != ModeOuter {
+		lines = append(lines, "mode "+string(o.mode))
+	}
+	if o.viewTransition {
+		lines = append(lines, "useViewTransition true")
+	}
+	if r != nil {
+		var b strings.Builder
+		if err := renderContext(s.ctx, &b, r); err != nil {
+			return err
+		}
+		// SSE ends a line at \r as well as \n, so both are split into data lines.
+		markup := strings.ReplaceAll(b.String(), "\r\n", "\n")
+		markup = strings.ReplaceAll(markup, "\r", "\n")
+		for _, line := range strings.Split(markup, "\n") {
+			lines = append(lines, "elements "+line)
+		}
+	}
+	return s.send(EventPatchElements, lines)
+}
+
+// RemoveElements sends a datastar-patch-elements event removing the elements matching selector.
+func (s *SSE) RemoveElements(selector string, opts ...PatchOption) error {
+	return s.PatchElements(nil, append(opts, WithSelector(selector), WithMode(ModeRemove))...)
+}
+
+// SendPatches sends each patch, such as those returned by Diff, as a
+// datastar-patch-elements event, stopping at the first error.
+func (s *SSE) SendPatches(patches []ElementPatch) error {
+	for _, p := range patches {
+		opts := []PatchOption{WithMode(p.Mode)}
+		if p.Selector != "" {
+			opts = append(opts, WithSelector(p.Selector))
+		}
+		if err := s.PatchElements(p.Elements, opts...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PatchSignals marshals v to JSON and sends it as a datastar-patch-signals event.
+// Fields set to null remove the corresponding signals.
+func (s *SSE) PatchSignals(v any, opts ...SignalOption) error {
+	var o signalOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var lines []string
+	if o.onlyIfMissing {
+		lines = append(lines, "onlyIfMissing true")
+	}
+	lines = append(lines, "signals "+string(data))
+	return s.send(EventPatchSignals, lines)
+}
+
+// checkEventField returns an error if value contains a control character, which
+// could end the data line and inject further event fields.
+func checkEventField(name, value string) error {
+	for _, c := range value {
+		if c < 0x20 || c == 0x7F {
+			return fmt.Errorf("htma: %s %q contains a control character", name, value)
+		}
+	}
+	return nil
+}
+
+// send writes one event and flushes it to the client.
+func (s *SSE) send(event string, lines []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	if err := s.ctx.Err(); err != nil {
+		return err
+	}
+	var b strings.Builder
+	b.WriteString("event: ")
+	b.WriteString(event)
+	b.WriteString("\n")
+	for _, line := range lines {
+		b.WriteString("data: ")
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+	b.WriteString("\n")
+	if _, err := io.WriteString(s.w, b.String()); err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
@@ -0,0 +1,193 @@
+package htma
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newTestSSE(t *testing.T, ctx context.Context) (*SSE, *httptest.ResponseRecorder) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/events", nil).WithContext(ctx)
+	rec := httptest.NewRecorder()
+	return NewSSE(rec, req), rec
+}
+
+func TestSSEHeaders(t *testing.T) {
+	_, rec := newTestSSE(t, context.Background())
+	if got := rec.Header().Get("Content-Type"); got != "text/event-stream" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	if got := rec.Header().Get("Cache-Control"); got != "no-cache" {
+		t.Errorf("Cache-Control = %q", got)
+	}
+	if !rec.Flushed {
+		t.Error("headers were not flushed")
+	}
+}
+
+func TestSSEPatchElements(t *testing.T) {
+	tests := []struct {
+		name string
+		send func(s *SSE) error
+		want string
+	}{
+		{
+			"default",
+			func(s *SSE) error {
+				return s.PatchElements(FlightCard().IDAttr("UA123").StatusTextAttr("Boarding"))
+			},
+			"event: datastar-patch-elements\n" +
+				"data: elements <flight-card id=\"UA123\" status-text=\"Boarding\"></flight-card>\n\n",
+		},
+		{
+			"selector and mode",
+			func(s *SSE) error {
+				return s.PatchElements(Li().Text("UA456"), WithSelector("#departures"), WithMode(ModeAppend), WithViewTransition())
+			},
+			"event: datastar-patch-elements\n" +
+				"data: selector #departures\n" +
+				"data: mode append\n" +
+				"data: useViewTransition true\n" +
+				"data: elements <li>UA456</li>\n\n",
+		},
+		{
+			"patches from a diff",
+			func(s *SSE) error {
+				old := Ul().IDAttr("rows").AddChild(Li().IDAttr("UA1").Text("UA1"), Li().IDAttr("AA3").Text("AA3"))
+				return s.SendPatches(Diff(old, Ul().IDAttr("rows").AddChild(Li().IDAttr("UA1").Text("UA1"), Li().IDAttr("DL2").Text("DL2"))))
+			},
+			"event: datastar-patch-elements\n" +
+				"data: selector #AA3\n" +
+				"data: mode remove\n\n" +
+				"event: datastar-patch-elements\n" +
+				"data: selector #rows\n" +
+				"data: mode append\n" +
+				"data: elements <li id=\"DL2\">DL2</li>\n\n",
+		},
+		{
+			"multi-line markup",
+			func(s *SSE) error {
+				return s.PatchElements(Pre().IDAttr("log").Text("line 1\nline 2"), WithMode(ModeInner))
+			},
+			"event: datastar-patch-elements\n" +
+				"data: mode inner\n" +
+				"data: elements <pre id=\"log\">line 1\n" +
+				"data: elements line 2</pre>\n\n",
+		},
+		{
+			"carriage returns",
+			func(s *SSE) error {
+				return s.PatchElements(Pre().IDAttr("log").Text("a\rb\r\nc"))
+			},
+			"event: datastar-patch-elements\n" +
+				"data: elements <pre id=\"log\">a\n" +
+				"data: elements b\n" +
+				"data: elements c</pre>\n\n",
+		},
+		{
+			"remove",
+			func(s *SSE) error { return s.RemoveElements("#UA123") },
+			"event: datastar-patch-elements\n" +
+				"data: selector #UA123\n" +
+				"data: mode remove\n\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, rec := newTestSSE(t, context.Background())
+			if err := tt.send(s); err != nil {
+				t.Fatalf("send: %v", err)
+			}
+			if got := rec.Body.String(); got != tt.want {
+				t.Errorf("got:\n%q\nwant:\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSSEUsesContextOptions(t *testing.T) {
+	s, rec := newTestSSE(t, WithOptions(context.Background(), Options{Indent: "  "}))
+	if err := s.PatchElements(Ul().IDAttr("rows").AddChild(Li().Text("UA1"))); err != nil {
+		t.Fatal(err)
+	}
+	want := "event: datastar-patch-elements\n" +
+		"data: elements <ul id=\"rows\">\n" +
+		"data: elements   <li>UA1</li>\n" +
+		"data: elements </ul>\n\n"
+	if got := rec.Body.String(); got != want {
+		t.Errorf("got:\n%q\nwant:\n%q", got, want)
+	}
+}
+
+func TestSSERejectsControlCharacters(t *testing.T) {
+	s, rec := newTestSSE(t, context.Background())
+	if err := s.PatchElements(Div(), WithSelector("#a\ndata: mode remove")); err == nil {
+		t.Error("selector with newline accepted")
+	}
+	if err := s.PatchElements(Div(), WithMode("inner\rx")); err == nil {
+		t.Error("mode with carriage return accepted")
+	}
+	if err := s.RemoveElements("#a\r\n"); err == nil {
+		t.Error("remove selector with newline accepted")
+	}
+	if got := rec.Body.String(); got != "" {
+		t.Errorf("events written: %q", got)
+	}
+}
+
+func TestSSEPatchSignals(t *testing.T) {
+	s, rec := newTestSSE(t, context.Background())
+	type search struct {
+		From string `json:"from"`
+		To   string `json:"to"`
+	}
+	if err := s.PatchSignals(search{From: "SFO", To: "EWR"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.PatchSignals(map[string]bool{"loading": true}, OnlyIfMissing()); err != nil {
+		t.Fatal(err)
+	}
+	want := "event: datastar-patch-signals\n" +
+		"data: signals {\"from\":\"SFO\",\"to\":\"EWR\"}\n\n" +
+		"event: datastar-patch-signals\n" +
+		"data: onlyIfMissing true\n" +
+		"data: signals {\"loading\":true}\n\n"
+	if got := rec.Body.String(); got != want {
+		t.Errorf("got:\n%q\nwant:\n%q", got, want)
+	}
+}
+
+func TestSSEStopsWhenContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s, rec := newTestSSE(t, ctx)
+	cancel()
+	if err := s.PatchElements(Div().IDAttr("a")); !errors.Is(err, context.Canceled) {
+		t.Errorf("PatchElements error = %v, want context.Canceled", err)
+	}
+	if err := s.PatchSignals(map[string]int{"n": 1}); !errors.Is(err, context.Canceled) {
+		t.Errorf("PatchSignals error = %v, want context.Canceled", err)
+	}
+	if rec.Body.Len() != 0 {
+		t.Errorf("wrote %q after cancellation", rec.Body.String())
+	}
+}
+
+// unflushableWriter hides the Flush method of the writer it wraps, as some logging
+// middleware does.
+type unflushableWriter struct {
+	http.ResponseWriter
+}
+
+func TestSSEReportsUnflushableWriter(t *testing.T) {
+	rec := httptest.NewRecorder()
+	s := NewSSE(unflushableWriter{rec}, httptest.NewRequest(http.MethodGet, "/events", nil))
+	if err := s.PatchElements(Div().IDAttr("a")); !errors.Is(err, http.ErrNotSupported) {
+		t.Errorf("PatchElements error = %v, want http.ErrNotSupported", err)
+	}
+	if rec.Body.Len() != 0 {
+		t.Errorf("wrote %q to a writer that cannot flush", rec.Body.String())
+	}
+}
@@ -0,0 +1,99 @@
+package htma
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrInvalidName is returned for tag and attribute names that cannot be written
+// into markup safely.
+var ErrInvalidName = errors.New("htma: invalid name")
+
+// ValidateTagName reports whether tag is a valid element name. Names made only of
+// ASCII letters and digits, such as div, h1 or the SVG element path, are accepted,
+// as are custom element names such as flight-card. A custom element name starts with
+// a lowercase letter, contains a hyphen, and has no ASCII uppercase letters.
+func ValidateTagName(tag string) error {
+	if tag == "" {
+		return fmt.Errorf("%w: empty tag name", ErrInvalidName)
+	}
+	if !isASCIILetter(tag[0]) {
+		return fmt.Errorf("%w: tag %q must start with an ASCII letter", ErrInvalidName, tag)
+	}
+	alnum := true
+	for i := 0; i < len(tag); i++ {
+		if !isASCIILetter(tag[i]) && !('0' <= tag[i] && tag[i] <= '9') {
+			alnum = false
+			break
+		}
+	}
+	if alnum {
+		return nil
+	}
+	if !strings.Contains(tag, "-") {
+		return fmt.Errorf("%w: tag %q: custom element names must contain a hyphen", ErrInvalidName, tag)
+	}
+	if tag[0] < 'a' || tag[0] > 'z' {
+		return fmt.Errorf("%w: tag %q: custom element names must start with a lowercase letter", ErrInvalidName, tag)
+	}
+	for _, c := range tag {
+		if !isPCENChar(c) {
+			return fmt.Errorf("%w: tag %q: %q is not allowed in custom element names", ErrInvalidName, tag, c)
+		}
+	}
+	return nil
+}
+
+// isPCENChar reports whether c may appear in a custom element name.
+func isPCENChar(c rune) bool {
+	switch {
+	case c == '-' || c == '.' || c == '_' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z':
+		return true
+	case c == 0xB7:
+		return true
+	case 0xC0 <= c && c <= 0xD6, 0xD8 <= c && c <= 0xF6, 0xF8 <= c && c <= 0x37D:
+		return true
+	case 0x37F <= c && c <= 0x1FFF, 0x200C <= c && c <= 0x200D, 0x203F <= c && c <= 0x2040:
+		return true
+	case 0x2070 <= c && c <= 0x218F, 0x2C00 <= c && c <= 0x2FEF, 0x3001 <= c && c <= 0xD7FF:
+		return true
+	case 0xF900 <= c && c <= 0xFDCF, 0xFDF0 <= c && c <= 0xFFFD, 0x10000 <= c && c <= 0xEFFFF:
+		return true
+	}
+	return false
+}
+
+// ValidateAttrName reports whether name is a valid attribute name. Following the HTML
+// syntax, a name may not be empty or contain whitespace, control characters,
+// noncharacters, or any of " ' > / =. The < character, which browsers treat as a
+// parse error, is rejected as well.
+func ValidateAttrName(name string) error {
+	if name == "" {
+		return fmt.Errorf("%w: empty attribute name", ErrInvalidName)
+	}
+	for i, c := range name {
+		if c == utf8.RuneError {
+			if _, size := utf8.DecodeRuneInString(name[i:]); size == 1 {
+				return fmt.Errorf("%w: attribute %q is not valid UTF-8", ErrInvalidName, name)
+			}
+		}
+		if isInvalidAttrNameChar(c) {
+			return fmt.Errorf("%w: %q is not allowed in attribute %q", ErrInvalidName, c, name)
+		}
+	}
+	return nil
+}
+
+func isInvalidAttrNameChar(c rune) bool {
+	switch {
+	case c <= 0x20, 0x7F <= c && c <= 0x9F:
+		return true // controls and space
+	case c == '"' || c == '\'' || c == '>' || c == '/' || c == '=' || c == '<':
+		return true
+	case 0xFDD0 <= c && c <= 0xFDEF, c&0xFFFE == 0xFFFE:
+		return true // noncharacters
+	}
+	return false
+}
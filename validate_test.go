@@ -0,0 +1,174 @@
+package htma
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateTagName(t *testing.T) {
+	valid := []string{"div", "h1", "path", "foreignObject", "flight-card", "md-filled-button", "x-ü", "my.el-1_2"}
+	for _, tag := range valid {
+		if err := ValidateTagName(tag); err != nil {
+			t.Errorf("ValidateTagName(%q): %v", tag, err)
+		}
+	}
+	invalid := []string{"", "1a", "-x", "flight_card", "Flight-Card", "x-y z", "x-<y", "div onclick=x", "x-y>"}
+	for _, tag := range invalid {
+		if err := ValidateTagName(tag); !errors.Is(err, ErrInvalidName) {
+			t.Errorf("ValidateTagName(%q) error = %v, want ErrInvalidName", tag, err)
+		}
+	}
+}
+
+func TestValidateAttrName(t *testing.T) {
+	valid := []string{"class", "data-on:click__debounce.500ms", "@click", ":class", "x-bind:href", "aria-label", "é"}
+	for _, name := range valid {
+		if err := ValidateAttrName(name); err != nil {
+			t.Errorf("ValidateAttrName(%q): %v", name, err)
+		}
+	}
+	invalid := []string{"", `onclick="x" a`, "a b", "a=b", "a>", "a/", `a"`, "a'", "a<b", "a\x00", "a\x7f", "a﷐", "a\xff"}
+	for _, name := range invalid {
+		if err := ValidateAttrName(name); !errors.Is(err, ErrInvalidName) {
+			t.Errorf("ValidateAttrName(%q) error = %v, want ErrInvalidName", name, err)
+		}
+	}
+}
+
+func TestAttrKeepsInvalidNamesUntilRender(t *testing.T) {
+	setters := map[string]Element{
+		"Attr":     Div().Attr(`onclick="alert(1)" a`, ""),
+		"BoolAttr": Div().BoolAttr("a b", true),
+		"DataAttr": Div().DataAttr("x>", ""),
+	}
+	for name, el := range setters {
+		if len(el.attrs) != 1 {
+			t.Errorf("%s with an invalid name: attributes = %+v", name, el.attrs)
+		}
+		if _, err := RenderString(el); !errors.Is(err, ErrInvalidName) {
+			t.Errorf("%s with an invalid name: render error = %v, want ErrInvalidName", name, err)
+		}
+		if got := el.RenderWith(Options{Lenient: true}); got != "<div></div>" {
+			t.Errorf("%s with an invalid name: lenient render = %s", name, got)
+		}
+	}
+}
+
+func TestRenderInvalidNames(t *testing.T) {
+	el := Section().AddChild(
+		Span().Text("UA1"),
+		Div().ClassAttr("card").Attr(`onclick="alert(1)" a`, ""),
+		Element{tag: "x y"}.AddChild(Content("inner")),
+	)
+
+	var b strings.Builder
+	err := el.RenderStream(&b)
+	if !errors.Is(err, ErrInvalidName) {
+		t.Fatalf("strict error = %v, want ErrInvalidName", err)
+	}
+	if got, want := b.String(), "<section><span>UA1</span>"; got != want {
+		t.Errorf("strict render wrote %s, want %s", got, want)
+	}
+
+	want := `<section><span>UA1</span><div class="card"></div>inner</section>`
+	if got := el.RenderWith(Options{Lenient: true}); got != want {
+		t.Errorf("lenient got %s, want %s", got, want)
+	}
+	b.Reset()
+	if err := el.RenderContext(WithOptions(context.Background(), Options{Lenient: true}), &b); err != nil {
+		t.Fatal(err)
+	}
+	if got := b.String(); got != want {
+		t.Errorf("lenient from context got %s, want %s", got, want)
+	}
+
+	// Explicit options replace the context's, so they can turn Lenient back off.
+	ctx := WithOptions(context.Background(), Options{Lenient: true})
+	if r := newRenderer(ctx, &b, Options{Indent: "  "}); r.opts.Lenient {
+		t.Error("explicit options without Lenient rendered leniently")
+	}
+}
+
+func TestCustomElementPanicsOnInvalidTag(t *testing.T) {
+	if got := CustomElement("flight-card").Render(); got != "<flight-card></flight-card>" {
+		t.Errorf("got %s", got)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("CustomElement(\"flight_card\") did not panic")
+		}
+	}()
+	CustomElement("flight_card")
+}
+
+func TestParseRejectsInvalidNames(t *testing.T) {
+	for _, input := range []string{"<div a\x01b=\"x\"></div>", "<a&b></a&b>"} {
+		if _, err := ParseFragment(strings.NewReader(input)); err == nil {
+			t.Errorf("ParseFragment(%q) succeeded, want error", input)
+		}
+	}
+}
+
+// FuzzValidateAttrName checks that every name accepted by ValidateAttrName renders as
+// exactly one attribute, and that rejected names never reach the output.
+func FuzzValidateAttrName(f *testing.F) {
+	for _, seed := range []string{"class", "data-on:click", "@click", `onclick="x" a`, "a>b", "a\x00", "é"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, name string) {
+		if err := ValidateAttrName(name); err != nil {
+			el := Div().Attr(name, "v")
+			if got := el.RenderWith(Options{Lenient: true}); got != "<div></div>" {
+				t.Fatalf("lenient render of invalid name %q = %s", name, got)
+			}
+			if _, err := RenderString(el); !errors.Is(err, ErrInvalidName) {
+				t.Fatalf("strict render of invalid name %q: error = %v", name, err)
+			}
+			return
+		}
+		html, err := RenderString(Div().Attr(name, "v"))
+		if err != nil {
+			t.Fatalf("render valid name %q: %v", name, err)
+		}
+		nodes, err := ParseFragment(strings.NewReader(html))
+		if err != nil {
+			t.Fatalf("parse %s: %v", html, err)
+		}
+		if len(nodes) != 1 {
+			t.Fatalf("parse %s: got %d nodes", html, len(nodes))
+		}
+		attrs := nodes[0].(Element).attrs
+		if len(attrs) != 1 || attrs[0].key != strings.ToLower(name) || attrs[0].value != "v" {
+			t.Fatalf("parse %s: got attributes %+v", html, attrs)
+		}
+	})
+}
+
+// FuzzValidateTagName checks that every tag accepted by ValidateTagName renders as a
+// single element with that tag.
+func FuzzValidateTagName(f *testing.F) {
+	for _, seed := range []string{"div", "flight-card", "md-icon", "x y", "a>b", "Flight-Card", "svg"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, tag string) {
+		if err := ValidateTagName(tag); err != nil {
+			if got := (Element{tag: tag, text: "x"}).RenderWith(Options{Lenient: true}); got != "x" {
+				t.Fatalf("lenient render of invalid tag %q = %s", tag, got)
+			}
+			return
+		}
+		html, err := RenderString(CustomElement(tag))
+		if err != nil {
+			t.Fatalf("render valid tag %q: %v", tag, err)
+		}
+		nodes, err := ParseFragment(strings.NewReader(html))
+		if err != nil {
+			t.Fatalf("parse %s: %v", html, err)
+		}
+		if len(nodes) != 1 || nodes[0].(Element).tag != strings.ToLower(tag) {
+			t.Fatalf("parse %s: got %#v", html, nodes)
+		}
+	})
+}
@@ -0,0 +1,99 @@
+package htma
+
+import "errors"
+
+// SkipChildren is used as a return value from a Walk callback to indicate that the
+// children of the current node are to be skipped. It is not returned as an error by Walk.
+var SkipChildren = errors.New("skip children")
+
+// Walk visits r and its descendants in document order, calling fn for each node.
+// If fn returns SkipChildren for an element, its children are not visited; any other
+// error stops the walk and is returned. Fragments are transparent: fn is called for
+// their nodes but not for the fragment itself.
+func Walk(r Renderable, fn func(n Renderable) error) error {
+	err := walk(r, fn)
+	if err == SkipChildren {
+		return nil
+	}
+	return err
+}
+
+func walk(r Renderable, fn func(n Renderable) error) error {
+	if f, ok := r.(Fragment); ok {
+		for _, c := range f {
+			if err := walk(c, fn); err != nil && err != SkipChildren {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := fn(r); err != nil {
+		return err
+	}
+	e, ok := r.(Element)
+	if !ok {
+		return nil
+	}
+	for _, c := range e.children {
+		if err := walk(c, fn); err != nil && err != SkipChildren {
+			return err
+		}
+	}
+	return nil
+}
+
+// FindAll returns the elements in the tree rooted at r for which match returns true,
+// in document order.
+func FindAll(r Renderable, match func(e Element) bool) []Element {
+	var found []Element
+	Walk(r, func(n Renderable) error {
+		if e, ok := n.(Element); ok && match(e) {
+			found = append(found, e)
+		}
+		return nil
+	})
+	return found
+}
+
+// errFound stops a walk once the element being searched for is found.
+var errFound = errors.New("found")
+
+// FindByID returns the first element in the tree rooted at r with the given id.
+func FindByID(r Renderable, id string) (Element, bool) {
+	var found Element
+	err := Walk(r, func(n Renderable) error {
+		if e, ok := n.(Element); ok {
+			if v, ok := e.getAttr("id"); ok && v == id {
+				found = e
+				return errFound
+			}
+		}
+		return nil
+	})
+	return found, err == errFound
+}
+
+// Transform returns a copy of the tree rooted at r in which every element has been
+// passed through fn. Children are transformed before their parent, so fn sees the
+// already transformed children. The original tree is left unchanged.
+func Transform(r Renderable, fn func(e Element) Element) Renderable {
+	if f, ok := r.(Fragment); ok {
+		out := make(Fragment, len(f))
+		for i, c := range f {
+			out[i] = Transform(c, fn)
+		}
+		return out
+	}
+	e, ok := r.(Element)
+	if !ok {
+		return r
+	}
+	if len(e.children) > 0 {
+		children := make([]Renderable, len(e.children))
+		for i, c := range e.children {
+			children[i] = Transform(c, fn)
+		}
+		e.children = children
+	}
+	return fn(e)
+}
@@ -0,0 +1,129 @@
+package htma
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func testPage() Element {
+	return HTML().AddChild(
+		Body().AddChild(
+			Nav().AddChild(
+				A().HrefAttr("/").Text("Home"),
+				A().HrefAttr("https://example.com").Text("Partner"),
+			),
+			Main().IDAttr("main").AddChild(
+				Img().SrcAttr("/hero.png"),
+				ResultsCard().DataOnLoadAttr("@get('/flights')").AddChild(
+					FlightCard().IDAttr("UA123").DataAttr("ident", "UA123"),
+					FlightCard().IDAttr("UA456"),
+				),
+				Img().SrcAttr("/map.png"),
+				Img().SrcAttr("/ad.png"),
+			),
+		),
+	)
+}
+
+func TestWalkVisitsInDocumentOrder(t *testing.T) {
+	var tags []string
+	err := Walk(testPage(), func(n Renderable) error {
+		if e, ok := n.(Element); ok {
+			tags = append(tags, e.Tag())
+			if e.Tag() == "results-card" {
+				return SkipChildren
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "html body nav a a main img results-card img img"
+	if got := strings.Join(tags, " "); got != want {
+		t.Errorf("got %s\nwant %s", got, want)
+	}
+}
+
+func TestWalkStopsOnError(t *testing.T) {
+	stop := errors.New("stop")
+	count := 0
+	err := Walk(testPage(), func(n Renderable) error {
+		count++
+		if e, ok := n.(Element); ok && e.Tag() == "nav" {
+			return stop
+		}
+		return nil
+	})
+	if err != stop {
+		t.Errorf("Walk error = %v, want %v", err, stop)
+	}
+	if count != 3 {
+		t.Errorf("visited %d nodes after stopping, want 3", count)
+	}
+}
+
+func TestFind(t *testing.T) {
+	page := testPage()
+	card, ok := FindByID(page, "UA456")
+	if !ok || card.Tag() != "flight-card" {
+		t.Errorf("FindByID = %v, %v", card.Render(), ok)
+	}
+	if _, ok := FindByID(page, "missing"); ok {
+		t.Error("FindByID found a missing id")
+	}
+	if e, ok := FindByID(page, ""); ok {
+		t.Errorf("FindByID with an empty id found %s", e.Render())
+	}
+	imgs := FindAll(page, func(e Element) bool { return e.Tag() == "img" })
+	if len(imgs) != 3 {
+		t.Errorf("FindAll found %d images, want 3", len(imgs))
+	}
+}
+
+func TestTransform(t *testing.T) {
+	page := testPage()
+	before := page.Render()
+
+	// Open external links safely.
+	out := Transform(page, func(e Element) Element {
+		if href, _ := e.GetAttr("href"); e.Tag() == "a" && strings.HasPrefix(href, "https://") {
+			return e.RelAttr("noopener")
+		}
+		return e
+	})
+
+	// Lazy-load every image after the first one.
+	seen := 0
+	out = Transform(out, func(e Element) Element {
+		if e.Tag() != "img" {
+			return e
+		}
+		seen++
+		if seen == 1 {
+			return e
+		}
+		return e.Attr("loading", "lazy")
+	})
+
+	// Strip Datastar directives and other data attributes for crawlers.
+	out = Transform(out, func(e Element) Element {
+		for k := range e.Attrs() {
+			if strings.HasPrefix(k, "data-") {
+				e = e.RemoveAttr(k)
+			}
+		}
+		return e
+	})
+
+	want := `<!DOCTYPE html><html><body><nav><a href="/">Home</a><a href="https://example.com" rel="noopener">Partner</a></nav>` +
+		`<main id="main"><img src="/hero.png"><results-card><flight-card id="UA123"></flight-card><flight-card id="UA456"></flight-card></results-card>` +
+		`<img src="/map.png" loading="lazy"><img src="/ad.png" loading="lazy"></main></body></html>`
+	if got := out.Render(); got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+	if page.Render() != before {
+		t.Error("Transform modified the original tree")
+	}
+}